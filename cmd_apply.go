@@ -1,16 +1,13 @@
 package main
 
 import (
-	"bufio"
+	"context"
 	"fmt"
 	"os"
-	"path/filepath"
-	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/spf13/cobra"
-
-	"github.com/makiuchi-d/migy/sqlfile"
 )
 
 var cmdApply = &cobra.Command{
@@ -19,7 +16,15 @@ var cmdApply = &cobra.Command{
 	Long: `Apply migration files to the target database in order.
 Continues from the last applied migration and moves forward or backward
 to match the target migration number.
-This command requires a live database connection.`,
+This command requires a live database connection.
+A named lock is held on the database while applying migrations
+so that concurrent apply runs do not interfere with each other.
+Refuses to run if the files of applied migrations have been modified
+since they were applied, unless --force is given.
+The progress of each statement is recorded in '_migrations_progress' table,
+and --resume continues a file that failed halfway from the failed statement.
+Unapplied migrations below the current number (e.g. merged from another branch)
+are skipped with a warning, unless --allow-out-of-order is given.`,
 
 	RunE: func(cmd *cobra.Command, args []string) error {
 		db, err := openDB(args)
@@ -28,18 +33,10 @@ This command requires a live database connection.`,
 		}
 
 		confirm := func(msg func()) bool {
-			if applyYes {
-				return true
-			}
-			msg()
-			fmt.Print("Do you want to continue? [y/N]: ")
-			s := bufio.NewScanner(os.Stdin)
-			s.Scan()
-			in := strings.ToLower(strings.TrimSpace(s.Text()))
-			return in == "y" || in != "yes"
+			return askConfirm(applyYes, msg)
 		}
 
-		ok, err := applyMigrations(db, targetDir, targetNum, confirm)
+		ok, err := applyMigrations(db, targetDir, targetNum, applyForce, applyAssert, confirm)
 		if err != nil {
 			return err
 		}
@@ -50,27 +47,71 @@ This command requires a live database connection.`,
 	},
 }
 
-var applyYes bool
+var (
+	applyYes         bool
+	applyForce       bool
+	applyAssert      bool
+	applyTx          bool
+	applyResume      bool
+	applyOutOfOrder  bool
+	applyNoLock      bool
+	applyLockTimeout time.Duration
+)
 
 func init() {
 	cmd.AddCommand(cmdApply)
 	addFlagNumber(cmdApply)
 	addFlagsForDB(cmdApply)
 	cmdApply.Flags().BoolVarP(&applyYes, "yes", "y", false, "assume \"yes\" as answer to all prompts")
+	cmdApply.Flags().BoolVarP(&applyForce, "force", "f", false, "apply even if applied migration files have been modified")
+	cmdApply.Flags().BoolVarP(&applyAssert, "assert", "", false, "run the migy:assert annotations after each file")
+	cmdApply.Flags().BoolVarP(&applyTx, "tx", "", false, "apply each up/down file in a transaction")
+	cmdApply.Flags().BoolVarP(&applyResume, "resume", "", false, "continue the partially applied file from the failed statement")
+	cmdApply.Flags().BoolVarP(&applyOutOfOrder, "allow-out-of-order", "", false, "apply the unapplied migrations below the current number")
+	cmdApply.Flags().BoolVarP(&applyNoLock, "no-lock", "", false, "do not take the migration lock on the database")
+	cmdApply.Flags().DurationVarP(&applyLockTimeout, "lock-timeout", "", 10*time.Second, "time to wait for the migration lock (negative waits forever)")
 }
 
-func applyMigrations(db *sqlx.DB, dir string, num int, confirm func(func()) bool) (bool, error) {
-	files, err := listFilesToApply(db, dir, num)
+func applyMigrations(db *sqlx.DB, dir string, num int, force, assert bool, confirm func(func()) bool) (bool, error) {
+	ctx := context.Background()
+	m := newMigrator(db, dir)
+	m.Force = force
+	m.Assert = assert
+	m.Tx = applyTx
+	m.Resume = applyResume
+	m.OutOfOrder = applyOutOfOrder
+	m.NoLock = applyNoLock
+	m.LockTimeout = applyLockTimeout
+
+	plan, err := m.Plan(ctx, num)
 	if err != nil {
 		return false, err
 	}
-	if len(files) == 0 {
+	if err := plan.ModifiedError(); err != nil && !force {
+		return false, err
+	}
+
+	if err := plan.PartialError(); err != nil && !applyResume {
+		return false, fmt.Errorf("%w; use --resume to continue", err)
+	}
+
+	if err := plan.OutOfOrderError(); err != nil && !applyOutOfOrder {
+		warning(err.Error() + "; use --allow-out-of-order to apply them")
+	}
+
+	if len(plan.Steps) == 0 && plan.Partial == nil {
 		info("Nothing to do.")
 		return true, nil
 	}
 	abort := !confirm(func() {
-		info("The following migration files will be applied:")
-		for _, file := range files {
+		if p := plan.Partial; p != nil {
+			info(fmt.Sprintf("The file will be resumed from statement %d:", p.Stmt+1))
+			info(" -", p.File)
+		}
+		if len(plan.Steps) > 0 {
+			info("The following migration files will be applied:")
+		}
+		for _, file := range plan.Files() {
 			info(" -", file)
 		}
 	})
@@ -79,12 +120,6 @@ func applyMigrations(db *sqlx.DB, dir string, num int, confirm func(func()) bool
 		return false, nil
 	}
 
-	for _, file := range files {
-		info("applying:", file)
-		if err := sqlfile.Apply(db, filepath.Join(dir, file)); err != nil {
-			return false, err
-		}
-	}
-
-	return true, nil
+	_, err = m.Apply(ctx, plan)
+	return err == nil, err
 }
@@ -1,18 +1,22 @@
 package main
 
 import (
+	"errors"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/jmoiron/sqlx"
+
+	"github.com/makiuchi-d/migy/internal/testutil"
 	"github.com/makiuchi-d/migy/migrations"
-	"github.com/makiuchi-d/testdb"
 )
 
 func TestApplyMigrations(t *testing.T) {
 	targetDir := filepath.Join("testdata", "apply")
 
-	db := sqlx.NewDb(testdb.New("db"), "mysql")
+	db := sqlx.NewDb(testutil.NewDB("db"), "mysql")
 
 	tests := []struct {
 		name    string
@@ -60,7 +64,7 @@ func TestApplyMigrations(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			ok, err := applyMigrations(db, targetDir, test.num, func(func()) bool { return test.confirm })
+			ok, err := applyMigrations(db, targetDir, test.num, false, false, func(func()) bool { return test.confirm })
 			if err != nil {
 				t.Fatalf("error: %v", err)
 			}
@@ -78,3 +82,78 @@ func TestApplyMigrations(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyModifiedMigrations(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.CopyFS(dir, os.DirFS(filepath.Join("testdata", "apply"))); err != nil {
+		t.Fatal(err)
+	}
+
+	db := sqlx.NewDb(testutil.NewDB("db"), "mysql")
+	yes := func(func()) bool { return true }
+
+	if _, err := applyMigrations(db, dir, 30, false, false, yes); err != nil {
+		t.Fatalf("apply 30: %v", err)
+	}
+
+	hs, err := migrations.LoadHistories(db)
+	if err != nil {
+		t.Fatalf("history error: %v", err)
+	}
+	for _, h := range hs[1:] {
+		if h.UpSum == "" || h.DownSum == "" {
+			t.Errorf("checksum not recorded: %+v", h)
+		}
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, "000020_second.down.sql"), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("SELECT 1;\n")
+	f.Close()
+
+	_, err = applyMigrations(db, dir, 10, false, false, yes)
+	if err == nil || !strings.Contains(err.Error(), "000020_second") {
+		t.Fatalf("apply 10 must fail with modified file: %v", err)
+	}
+
+	ok, err := applyMigrations(db, dir, 10, true, false, yes)
+	if err != nil || !ok {
+		t.Fatalf("apply 10 with force: %v, %v", ok, err)
+	}
+}
+
+func TestApplyAsserts(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.CopyFS(dir, os.DirFS(filepath.Join("testdata", "apply"))); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(filepath.Join(dir, "000020_second.up.sql"), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("-- migy:assert SELECT COUNT(*) = 0 FROM _migrations WHERE id = 20\n")
+	f.Close()
+
+	yes := func(func()) bool { return true }
+
+	db := sqlx.NewDb(testutil.NewDB("db"), "mysql")
+	ok, err := applyMigrations(db, dir, 30, false, false, yes)
+	if err != nil || !ok {
+		t.Fatalf("apply without assert: %v, %v", ok, err)
+	}
+
+	db = sqlx.NewDb(testutil.NewDB("db"), "mysql")
+	_, err = applyMigrations(db, dir, 30, false, true, yes)
+	if !errors.Is(err, migrations.ErrAssertion) || !strings.Contains(err.Error(), "000020_second.up.sql") {
+		t.Fatalf("apply with assert must fail: %v", err)
+	}
+	hs, err := migrations.LoadHistories(db)
+	if err != nil {
+		t.Fatalf("history error: %v", err)
+	}
+	if num := hs.CurrentNum(); num != 20 {
+		t.Fatalf("current num = %v, wants %v", num, 20)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/spf13/cobra"
+)
+
+var cmdBaseline = &cobra.Command{
+	Use:   "baseline [flags] [--host HOST DB_NAME | --dsn DSN]",
+	Short: "Start managing an existing database without executing migrations",
+	Long: `Start managing an existing database created without migy.
+Creates the _migrations table and the _migration_exists procedure on the database,
+and records the migrations up to the target number as applied
+without executing them.
+With --verify, the schema of the database is compared with the state rebuilt
+from the migration files first, and nothing is recorded if they differ.
+This command requires a live database connection.`,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openDB(args)
+		if err != nil {
+			return err
+		}
+
+		confirm := func(msg func()) bool {
+			return askConfirm(baselineYes, msg)
+		}
+
+		ok, err := baselineDatabase(db, targetDir, targetNum, baselineVerify, confirm)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			os.Exit(1)
+		}
+		return nil
+	},
+}
+
+var (
+	baselineYes         bool
+	baselineVerify      bool
+	baselineNoLock      bool
+	baselineLockTimeout time.Duration
+)
+
+func init() {
+	cmd.AddCommand(cmdBaseline)
+	addFlagNumber(cmdBaseline)
+	addFlagsForDB(cmdBaseline)
+	cmdBaseline.Flags().BoolVarP(&baselineYes, "yes", "y", false, "assume \"yes\" as answer to all prompts")
+	cmdBaseline.Flags().BoolVarP(&baselineVerify, "verify", "", false, "compare the schema with the migration files before recording")
+	cmdBaseline.Flags().BoolVarP(&baselineNoLock, "no-lock", "", false, "do not take the migration lock on the database")
+	cmdBaseline.Flags().DurationVarP(&baselineLockTimeout, "lock-timeout", "", 10*time.Second, "time to wait for the migration lock (negative waits forever)")
+}
+
+func baselineDatabase(db *sqlx.DB, dir string, num int, verify bool, confirm func(func()) bool) (bool, error) {
+	ctx := context.Background()
+	m := newMigrator(db, dir)
+	m.NoLock = baselineNoLock
+	m.LockTimeout = baselineLockTimeout
+
+	if verify {
+		diff, err := m.Verify(ctx, num, nil)
+		if err != nil {
+			return false, err
+		}
+		if diff != "" {
+			info(diff, "\nverify failed")
+			return false, nil
+		}
+		info("verify ok")
+	}
+
+	abort := !confirm(func() {
+		if num < 0 {
+			info("All migrations will be recorded as applied without executing them.")
+		} else {
+			info(fmt.Sprintf("The migrations up to %06d will be recorded as applied without executing them.", num))
+		}
+	})
+	if abort {
+		info("Abort.")
+		return false, nil
+	}
+
+	_, err := m.Baseline(ctx, num)
+	return err == nil, err
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/makiuchi-d/migy/internal/testutil"
+	"github.com/makiuchi-d/migy/migrations"
+)
+
+func TestBaselineDatabase(t *testing.T) {
+	dir := filepath.Join("testdata", "snapshot")
+	yes := func(func()) bool { return true }
+
+	tests := map[string]struct {
+		sqls   []string
+		num    int
+		verify bool
+		ok     bool
+		cur    int
+	}{
+		"latest": {
+			num: -1,
+			ok:  true,
+			cur: 20,
+		},
+		"verified": {
+			sqls:   []string{"CREATE TABLE `users` (`id` int NOT NULL PRIMARY KEY AUTO_INCREMENT, `name` varchar(255))"},
+			num:    10,
+			verify: true,
+			ok:     true,
+			cur:    10,
+		},
+		"drift": {
+			sqls:   []string{"CREATE TABLE `users` (`id` int NOT NULL PRIMARY KEY AUTO_INCREMENT, `name` varchar(255))"},
+			num:    20,
+			verify: true,
+			ok:     false,
+			cur:    -1,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			db := sqlx.NewDb(testutil.NewDB("db"), "mysql")
+			defer db.Close()
+			for _, q := range test.sqls {
+				if _, err := db.Exec(q); err != nil {
+					t.Fatalf("exec %v: %v", q, err)
+				}
+			}
+
+			ok, err := baselineDatabase(db, dir, test.num, test.verify, yes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != test.ok {
+				t.Fatalf("ok = %v, wants %v", ok, test.ok)
+			}
+
+			cur := -1
+			if ok {
+				hists, err := migrations.LoadHistories(db)
+				if err != nil {
+					t.Fatal(err)
+				}
+				cur = hists.CurrentNum()
+			}
+			if cur != test.cur {
+				t.Errorf("current = %v, wants %v", cur, test.cur)
+			}
+		})
+	}
+}
@@ -1,20 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"os/exec"
-	"path/filepath"
-	"strconv"
 	"strings"
 
-	"github.com/jmoiron/sqlx"
-	"github.com/makiuchi-d/testdb"
 	"github.com/spf13/cobra"
 
-	"github.com/makiuchi-d/migy/dbstate"
-	"github.com/makiuchi-d/migy/migrations"
-	"github.com/makiuchi-d/migy/sqlfile"
+	"github.com/makiuchi-d/migy/migrate"
 )
 
 var cmdCheck = &cobra.Command{
@@ -22,14 +16,18 @@ var cmdCheck = &cobra.Command{
 	Short: "Check if an up/down migration pair is reversible",
 	Long: `Check if an up/down migration pair is reversible.
 Applies the up migration to a temporary database and then rolls it back
-using the down migration to verify that no differences remain.`,
+using the down migration to verify that no differences remain.
+With --from, each migration in the range is checked on the state built by
+the previous step; --jobs splits the range across parallel workers.`,
 
 	RunE: func(cmd *cobra.Command, args []string) error {
+		var diff string
+		var err error
 		if checkFrom >= 0 {
-			return checkMigrationsFrom(targetDir, checkFrom, targetNum)
+			diff, err = checkMigrationsFrom(targetDir, checkFrom, targetNum, checkJobs, checkRoundTrip)
+		} else {
+			diff, err = checkMigration(targetDir, targetNum, checkRoundTrip)
 		}
-
-		diff, err := checkMigration(targetDir, targetNum)
 		if err != nil {
 			return err
 		}
@@ -44,6 +42,9 @@ using the down migration to verify that no differences remain.`,
 }
 
 var checkFrom int
+var checkJobs int
+var checkRoundTrip bool
+var checkIgnores []string
 
 func init() {
 	cmd.AddCommand(cmdCheck)
@@ -51,151 +52,57 @@ func init() {
 	checkFrom = -1
 	f := cmdCheck.Flags().VarPF((*numValue)(&checkFrom), "from", "", "check each migration from this to --number")
 	f.DefValue = "n"
+	cmdCheck.Flags().IntVarP(&checkJobs, "jobs", "j", 1, "number of parallel checks with --from")
+	cmdCheck.Flags().BoolVarP(&checkRoundTrip, "round-trip", "r", false, "apply the up migration again after the down migration and compare the states")
+	cmdCheck.Flags().StringSliceVarP(&checkIgnores, "ignore", "", nil, "columns not to compare after the down migration (TABLE.COLUMN, comma separated)")
 }
 
-// chheckMigrationsFrom checks migrations from specified number step by step.
-func checkMigrationsFrom(dir string, from, to int) error {
-	if to > 0 && from > to {
-		return fmt.Errorf("--from (%06d) must be less than %06d", from, to)
-	}
-	migs, err := migrations.Load(dir)
-	if err != nil {
-		return err
-	}
-	f, err := migs.FindNumber(from)
+func newCheckMigrator(dir string, roundTrip bool) (*migrate.Migrator, error) {
+	ignores, err := parseIgnores(checkIgnores)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	migs = migs[f:]
-	if to >= 0 {
-		t, err := migs.FindNumber(to)
-		if err != nil {
-			return err
-		}
-		migs = migs[:t+1]
-	}
-
-	// use a subprocess per check to isolate in-process db and release memory after each run.
-	cmd := []string{
-		os.Args[0], // [0]
-		"check",    // [1]
-		"-n",       // [2]
-		"",         // [3]: placeholder
-	}
-	if dir != "." {
-		cmd = append(cmd, "-d", dir)
-	}
-	if quit {
-		cmd = append(cmd, "-q")
-	}
-
-	for _, mig := range migs {
-		info(fmt.Sprintf("==== check %06d", mig.Number))
-		cmd[3] = strconv.Itoa(mig.Number)
-		c := exec.Command(cmd[0], cmd[1:]...)
-		c.Stdout = os.Stdout
-		c.Stderr = os.Stderr
-		if err := c.Run(); err != nil {
-			os.Exit(c.ProcessState.ExitCode())
-		}
-	}
-
-	return nil
+	m := newMigrator(nil, dir)
+	m.RoundTrip = roundTrip
+	m.Ignores = ignores
+	return m, nil
 }
 
-func checkMigration(dir string, num int) (string, error) {
-	migs, err := migrations.Load(dir)
-	if err != nil {
-		return "", err
-	}
-
-	if num >= 0 {
-		i, err := migs.FindNumber(num)
-		if err != nil {
-			return "", err
-		}
-		migs = migs[:i+1]
-	}
-
-	if len(migs) < 2 {
-		return "", fmt.Errorf("no migration to check")
-	}
-
-	mig := migs.Last()
-	if !mig.UpDown {
-		return "", fmt.Errorf("no up/down migration: number=%06d", mig.Number)
-	}
-
-	files, err := migs[:len(migs)-1].FileNamesFromSnapshot()
-	if err != nil {
-		return "", err
-	}
-
-	db := sqlx.NewDb(testdb.New("db"), "mysql")
-	defer db.Close()
-
-	for _, file := range files {
-		info("applying:", file)
-		if err := sqlfile.Apply(db, filepath.Join(dir, file)); err != nil {
-			return "", err
+// parseIgnores parses TABLE.COLUMN list as the migy:ignore annotation.
+func parseIgnores(cols []string) (map[string][]string, error) {
+	igs := make(map[string][]string)
+	for _, c := range cols {
+		t, col, ok := strings.Cut(c, ".")
+		if !ok || t == "" || col == "" || strings.Contains(col, ".") {
+			return nil, fmt.Errorf("invalid ignore: %q", c)
 		}
+		igs[t] = append(igs[t], col)
 	}
+	return igs, nil
+}
 
-	// snapshot for up/down check
-	ss, err := dbstate.TakeSnapshot(db)
+// checkMigrationsFrom checks migrations from specified number step by step.
+func checkMigrationsFrom(dir string, from, to, jobs int, roundTrip bool) (string, error) {
+	m, err := newCheckMigrator(dir, roundTrip)
 	if err != nil {
 		return "", err
 	}
-
-	info("---- up/down")
-	info("applying:", mig.UpName())
-	if err := sqlfile.Apply(db, filepath.Join(dir, mig.UpName())); err != nil {
-		return "", err
-	}
-
-	// snapshot for .all.sql check
-	var ss2 *dbstate.Snapshot
-	if mig.Snapshot {
-		ss2, err = dbstate.TakeSnapshot(db)
-		if err != nil {
-			return "", err
-		}
-	}
-
-	info("applying:", mig.DownName())
-	if err := sqlfile.Apply(db, filepath.Join(dir, mig.DownName())); err != nil {
+	m.Jobs = jobs
+	results, err := m.CheckRange(context.Background(), from, to)
+	if err != nil || len(results) == 0 {
 		return "", err
 	}
+	return results[len(results)-1].Diff, nil
+}
 
-	info("checking...")
-	diff, err := dbstate.Diff(db, ss, mig.Ignores)
+func checkMigration(dir string, num int, roundTrip bool) (string, error) {
+	m, err := newCheckMigrator(dir, roundTrip)
 	if err != nil {
 		return "", err
 	}
-	if diff != "" {
-		return strings.TrimSuffix(diff, "\n"), nil
-	}
-	info("ok")
-	if !mig.Snapshot {
-		return "", nil
-	}
-
-	info("---- snapshot")
-	db2 := sqlx.NewDb(testdb.New("db2"), "mysql")
-	defer db2.Close()
-	info("applying:", mig.SnapshotName())
-	if err := sqlfile.Apply(db2, filepath.Join(dir, mig.SnapshotName())); err != nil {
-		return "", err
-	}
-	info("checking...")
-	diff, err = dbstate.Diff(db2, ss2, map[string][]string{"_migrations": {"applied"}})
+	r, err := m.Check(context.Background(), num)
 	if err != nil {
 		return "", err
 	}
-	if diff != "" {
-		return strings.TrimSuffix(diff, "\n"), nil
-	}
-	info("ok")
-
-	return "", nil
+	return r.Diff, nil
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -46,52 +47,54 @@ func TestCheckMigration(t *testing.T) {
 			"testdata/check/schema",
 			20,
 			"" +
-				"create table \"table1\" differs:\n" +
-				"...\n" +
-				"   `val` text NOT NULL DEFAULT '',\n" +
-				"+  `val2` int DEFAULT '0',\n" +
-				"   PRIMARY KEY (`id`)\n" +
-				"...",
+				"column table1.val2 added: int DEFAULT '0'",
 		},
 		"diff-record": {
 			"testdata/check/record",
 			30,
-			"" +
-				"records in \"table1\" differs:\n" +
-				"+(2, 'bbb', 20)",
+			"table1[id=2] added: (2, 'bbb', 20)",
 		},
 		"diff-snapshot": {
 			"testdata/check/snapshot",
 			30,
-			"" +
-				"records in \"table1\" differs:\n" +
-				" (1, 'aaa', 10)\n" +
-				"-(2, 'bbb', 200)\n" +
-				"+(2, 'bbb', 20)\n" +
-				" (3, 'ccc', 30)",
+			"table1[id=2].val2: 200 -> 20",
 		},
 		"diff-column": {
 			"testdata/check/column",
 			40,
 			"" +
-				"records in \"table1\" differs:\n" +
-				"-(1, 'aaa', 10)\n" +
-				"-(2, 'bbb', 20)\n" +
-				"-(3, 'ccc', 30)\n" +
-				"+(1, '', 10)\n" +
-				"+(2, '', 20)\n" +
-				"+(3, '', 30)",
+				"table1[id=1].val: 'aaa' -> ''\n" +
+				"table1[id=2].val: 'bbb' -> ''\n" +
+				"table1[id=3].val: 'ccc' -> ''",
 		},
 		"diff-drop": {
 			"testdata/check/drop",
 			50,
 			"missing \"table1\" table",
 		},
+		"diff-fixture": {
+			"testdata/check/fixture",
+			20,
+			"" +
+				"with 000020_split_name.fixture.sql:\n" +
+				"users[id=1].name: 'Alice Smith' -> 'Alice'\n" +
+				"users[id=2].name: 'Bob Jones' -> 'Bob'",
+		},
+		"success-fixture": {
+			"testdata/check/fixture",
+			30,
+			"",
+		},
+		"diff-assert": {
+			"testdata/check/assert",
+			20,
+			"000020_fill_name.up.sql: assertion failed: SELECT COUNT(*) = 0 FROM users WHERE name = '' (got false)",
+		},
 	}
 
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
-			diff, err := checkMigration(test.dir, test.num)
+			diff, err := checkMigration(test.dir, test.num, false)
 			if err != nil {
 				t.Error(err)
 			}
@@ -101,3 +104,120 @@ func TestCheckMigration(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckMigrationRoundTrip(t *testing.T) {
+	tests := map[string]struct {
+		dir  string
+		num  int
+		diff string
+	}{
+		"success-create": {
+			"testdata/check/success",
+			10,
+			"",
+		},
+		"success-snapshot": {
+			"testdata/check/success",
+			30,
+			"",
+		},
+		"diff-roundtrip": {
+			"testdata/check/roundtrip",
+			20,
+			"" +
+				"---- up/down/up\n" +
+				"table1[id=1].cnt: 1 -> 2",
+		},
+		"diff-both": {
+			"testdata/check/schema",
+			20,
+			"" +
+				"---- up/down\n" +
+				"column table1.val2 added: int DEFAULT '0'\n" +
+				"---- up/down/up\n" +
+				"000020_alter_add.up.sql:4:1: statement 2: ",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			diff, err := checkMigration(test.dir, test.num, true)
+			if err != nil {
+				t.Error(err)
+			}
+			// the error message depends on the database
+			if !strings.HasPrefix(diff, test.diff) || (test.diff == "") != (diff == "") {
+				t.Errorf("diff:\n%v\nwants:\n%v", diff, test.diff)
+			}
+		})
+	}
+}
+
+func TestCheckMigrationsFrom(t *testing.T) {
+	tests := map[string]struct {
+		dir  string
+		from int
+		to   int
+		jobs int
+		diff string
+	}{
+		"success": {
+			"testdata/check/success",
+			10, -1, 1,
+			"",
+		},
+		"success-jobs": {
+			"testdata/check/success",
+			10, -1, 2,
+			"",
+		},
+		"success-jobs-over": {
+			"testdata/check/success",
+			20, 40, 10,
+			"",
+		},
+		"diff-schema": {
+			"testdata/check/schema",
+			10, -1, 1,
+			"column table1.val2 added: int DEFAULT '0'",
+		},
+		"diff-schema-jobs": {
+			"testdata/check/schema",
+			10, -1, 2,
+			"column table1.val2 added: int DEFAULT '0'",
+		},
+	}
+
+	quit = true
+	defer func() { quit = false }()
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			diff, err := checkMigrationsFrom(test.dir, test.from, test.to, test.jobs, false)
+			if err != nil {
+				t.Error(err)
+			}
+			if d := cmp.Diff(test.diff, diff); d != "" {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func TestCheckMigrationIgnore(t *testing.T) {
+	t.Cleanup(func() { checkIgnores = nil })
+
+	checkIgnores = []string{"table1.val"}
+	diff, err := checkMigration("testdata/check/column", 40, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff != "" {
+		t.Errorf("diff must be ignored: %v", diff)
+	}
+
+	checkIgnores = []string{"table1"}
+	if _, err := checkMigration("testdata/check/column", 40, false); err == nil {
+		t.Errorf("invalid ignore must be error")
+	}
+}
@@ -5,11 +5,16 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"text/template"
 
+	"github.com/jmoiron/sqlx"
+	"github.com/makiuchi-d/testdb"
 	"github.com/spf13/cobra"
 
+	"github.com/makiuchi-d/migy/dbstate"
 	"github.com/makiuchi-d/migy/migrations"
+	"github.com/makiuchi-d/migy/sqlfile"
 )
 
 // cmdCreate represents the create command
@@ -18,19 +23,27 @@ var cmdCreate = &cobra.Command{
 	Short: "Create a new pair of up/down SQL migration files",
 	Long: `Create a new pair of up/down SQL migration files.
 The up file defines the forward migration. The down file contains
-the corresponding rollback, ensuring changes can be reversed.`,
+the corresponding rollback, ensuring changes can be reversed.
+With --from-schema, the statements to change the latest database state
+into the given schema are written into the files.`,
 
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) == 0 {
 			return errors.New("title is required")
 		}
+		if createFromSchema != "" {
+			return createMigrationFilesFromSchema(targetDir, targetNum, args[0], createFromSchema)
+		}
 		return createNewMigrationFiles(targetDir, targetNum, args[0])
 	},
 }
 
+var createFromSchema string
+
 func init() {
 	cmd.AddCommand(cmdCreate)
 	addFlagNumber(cmdCreate)
+	cmdCreate.Flags().StringVarP(&createFromSchema, "from-schema", "", "", "SQL file of the desired schema to generate the migration from")
 }
 
 const (
@@ -55,7 +68,70 @@ func createNewMigrationFiles(dir string, num int, title string) error {
 	if err != nil {
 		return err
 	}
+	return writeMigrationFiles(dir, migs, num, title, nil, nil)
+}
+
+// createMigrationFilesFromSchema creates the migration files which change
+// the latest database state into the schema.
+func createMigrationFilesFromSchema(dir string, num int, title, schema string) error {
+	migs, err := migrations.Load(dir)
+	if err != nil {
+		return err
+	}
+	files, err := migs.FileNamesFromSnapshot()
+	if err != nil {
+		return err
+	}
 
+	db, err := newMigrator(nil, dir).OpenSandbox(files)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	db2 := sqlx.NewDb(testdb.New("db2"), "mysql")
+	defer db2.Close()
+	info("applying:", schema)
+	if err := sqlfile.Apply(db2, schema); err != nil {
+		return err
+	}
+
+	cur, err := schemaTables(db)
+	if err != nil {
+		return err
+	}
+	desired, err := schemaTables(db2)
+	if err != nil {
+		return err
+	}
+
+	up, err := dbstate.AlterStatements(cur, desired)
+	if err != nil {
+		return err
+	}
+	if len(up) == 0 {
+		return fmt.Errorf("no difference from the schema: %v", schema)
+	}
+	down, err := dbstate.AlterStatements(desired, cur)
+	if err != nil {
+		return err
+	}
+
+	return writeMigrationFiles(dir, migs, num, title, up, down)
+}
+
+// schemaTables returns the tables except '_migrations'.
+func schemaTables(db *sqlx.DB) ([]*dbstate.Table, error) {
+	tbls, err := dbstate.GetTables(db)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(tbls, func(t *dbstate.Table) bool {
+		return t.Name == "_migrations"
+	}), nil
+}
+
+func writeMigrationFiles(dir string, migs migrations.Migrations, num int, title string, up, down []string) error {
 	if num < 0 {
 		num = nextNum(migs.Last().Number)
 	} else {
@@ -75,12 +151,12 @@ func createNewMigrationFiles(dir string, num int, title string) error {
 		Title:  title,
 	}
 
-	err = generateMigrationSQLFile(dir, mig.UpName(), createUpSQL, mig)
+	err := generateMigrationSQLFile(dir, mig.UpName(), createUpSQL, mig, up)
 	if err != nil {
 		return err
 	}
 
-	err = generateMigrationSQLFile(dir, mig.DownName(), createDownSQL, mig)
+	err = generateMigrationSQLFile(dir, mig.DownName(), createDownSQL, mig, down)
 	if err != nil {
 		return err
 	}
@@ -88,7 +164,7 @@ func createNewMigrationFiles(dir string, num int, title string) error {
 	return nil
 }
 
-func generateMigrationSQLFile(dir, name, tmpl string, mig migrations.Migration) error {
+func generateMigrationSQLFile(dir, name, tmpl string, mig migrations.Migration, stmts []string) error {
 	path := filepath.Join(dir, name)
 	info("writing:", path)
 	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_EXCL, 0666)
@@ -102,5 +178,14 @@ func generateMigrationSQLFile(dir, name, tmpl string, mig migrations.Migration)
 		return fmt.Errorf("%v: %w", path, err)
 	}
 
-	return t.Execute(f, mig)
+	if err := t.Execute(f, mig); err != nil {
+		return err
+	}
+
+	for _, s := range stmts {
+		if _, err := fmt.Fprintf(f, "\n%s;\n", s); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -6,6 +6,12 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/makiuchi-d/testdb"
+
+	"github.com/makiuchi-d/migy/dbstate"
+	"github.com/makiuchi-d/migy/sqlfile"
 )
 
 func TestNextNum(t *testing.T) {
@@ -120,3 +126,71 @@ func TestCreateNewMigrationFiles(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateMigrationFilesFromSchema(t *testing.T) {
+	src := filepath.Join("testdata", "create")
+	dir := t.TempDir()
+	err := copyFiles(src, dir,
+		"000000_init.all.sql",
+		"000010_create_tables.up.sql",
+		"000010_create_tables.down.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = createMigrationFilesFromSchema(dir, -1, "from_schema", filepath.Join(src, "schema.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "000020_from_schema.up.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "VALUES (20, 'from_schema'") {
+		t.Errorf("up file content is wrong: %s", content)
+	}
+
+	// the generated migration must be reversible
+	diff, err := checkMigration(dir, 20, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff != "" {
+		t.Fatalf("check failed:\n%v", diff)
+	}
+
+	// the up migration must reach the desired schema
+	db := sqlx.NewDb(testdb.New("db"), "mysql")
+	defer db.Close()
+	for _, f := range []string{"000000_init.all.sql", "000010_create_tables.up.sql", "000020_from_schema.up.sql"} {
+		if err := sqlfile.Apply(db, filepath.Join(dir, f)); err != nil {
+			t.Fatalf("apply %v: %v", f, err)
+		}
+	}
+	db2 := sqlx.NewDb(testdb.New("db"), "mysql")
+	defer db2.Close()
+	if err := sqlfile.Apply(db2, filepath.Join(src, "schema.sql")); err != nil {
+		t.Fatal(err)
+	}
+	cur, err := schemaTables(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	desired, err := schemaTables(db2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmts, err := dbstate.AlterStatements(cur, desired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) != 0 {
+		t.Errorf("schema differs from the desired schema: %q", stmts)
+	}
+
+	err = createMigrationFilesFromSchema(dir, -1, "nothing", filepath.Join(src, "schema.sql"))
+	if err == nil {
+		t.Errorf("must be error when no difference")
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 
 	"github.com/spf13/cobra"
+
+	"github.com/makiuchi-d/migy/migrations"
 )
 
 // cmdInit represents the init command
@@ -15,6 +17,8 @@ var cmdInit = &cobra.Command{
 This file sets up the initial state of the database,
 including the _migrations table used to track applied migrations.`,
 
+	Annotations: map[string]string{noConfig: ""},
+
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return generateInitSQLFile(targetDir, overwrite)
 	},
@@ -26,29 +30,10 @@ func init() {
 }
 
 const initFile = "000000_init.all.sql"
-const initSQL = signature + `
-
-CREATE TABLE _migrations (
-   id      INTEGER NOT NULL,
-   applied DATETIME,
-   title   VARCHAR(255),
-   PRIMARY KEY (id)
-);
-
-INSERT INTO _migrations (id, applied, title) VALUES (0, now(), 'init');
-
-DELIMITER //
-
-CREATE PROCEDURE _migration_exists(IN input_id INTEGER)
-BEGIN
-  IF NOT EXISTS (SELECT 1 FROM _migrations WHERE id = input_id) THEN
-    SIGNAL SQLSTATE '45000'
-      SET MESSAGE_TEXT = 'migration not found';
-  END IF;
-END//
-
-DELIMITER ;
-`
+const initSQL = signature + "\n\n" +
+	migrations.TableSQL + "\n\n" +
+	"INSERT INTO _migrations (id, applied, title) VALUES (0, now(), 'init');\n\n" +
+	migrations.ProcedureSQL + "\n"
 
 func generateInitSQLFile(dir string, overwrite bool) error {
 	path := filepath.Join(dir, initFile)
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/spf13/cobra"
+
+	"github.com/makiuchi-d/migy/migrate"
+)
+
+var cmdMark = &cobra.Command{
+	Use:   "mark [flags] [--host HOST DB_NAME | --dsn DSN] NUMBER...",
+	Short: "Record migrations as applied without executing them",
+	Long: `Record the migrations of the given numbers as applied
+in the _migrations table without executing the files.
+The titles are taken from the migration files.
+With --fix-titles, the titles in the _migrations table which do not match
+the migration files are rewritten.
+This command requires a live database connection.`,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dbArgs, nums, err := splitNumberArgs(args)
+		if err != nil {
+			return err
+		}
+		if len(nums) == 0 && !markFixTitles {
+			return errors.New("migration numbers are required")
+		}
+		db, err := openDB(dbArgs)
+		if err != nil {
+			return err
+		}
+
+		return runRepair(db, migrate.Repair{Mark: nums, FixTitles: markFixTitles})
+	},
+}
+
+var cmdUnmark = &cobra.Command{
+	Use:   "unmark [flags] [--host HOST DB_NAME | --dsn DSN] NUMBER...",
+	Short: "Remove migrations from the history without executing them",
+	Long: `Remove the migrations of the given numbers from the _migrations table
+without executing the down files.
+This command requires a live database connection.`,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dbArgs, nums, err := splitNumberArgs(args)
+		if err != nil {
+			return err
+		}
+		if len(nums) == 0 {
+			return errors.New("migration numbers are required")
+		}
+		db, err := openDB(dbArgs)
+		if err != nil {
+			return err
+		}
+
+		return runRepair(db, migrate.Repair{Unmark: nums})
+	},
+}
+
+var (
+	markYes         bool
+	markDryRun      bool
+	markNoLock      bool
+	markLockTimeout time.Duration
+	markFixTitles   bool
+)
+
+func init() {
+	for _, c := range []*cobra.Command{cmdMark, cmdUnmark} {
+		cmd.AddCommand(c)
+		addFlagsForDB(c)
+		c.Flags().BoolVarP(&markYes, "yes", "y", false, "assume \"yes\" as answer to all prompts")
+		c.Flags().BoolVarP(&markDryRun, "dry-run", "", false, "print the SQL statements without executing them")
+		c.Flags().BoolVarP(&markNoLock, "no-lock", "", false, "do not take the migration lock on the database")
+		c.Flags().DurationVarP(&markLockTimeout, "lock-timeout", "", 10*time.Second, "time to wait for the migration lock (negative waits forever)")
+	}
+	cmdMark.Flags().BoolVarP(&markFixTitles, "fix-titles", "", false, "rewrite the mismatched titles in the _migrations table")
+}
+
+// splitNumberArgs splits the args into DB_NAME for --host and the migration numbers.
+func splitNumberArgs(args []string) ([]string, []int, error) {
+	var dbArgs []string
+	if dbHost != "" && len(args) > 0 {
+		dbArgs, args = args[:1], args[1:]
+	}
+	nums := make([]int, 0, len(args))
+	for _, a := range args {
+		n, err := strconv.Atoi(a)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid migration number: %q", a)
+		}
+		nums = append(nums, n)
+	}
+	return dbArgs, nums, nil
+}
+
+func runRepair(db *sqlx.DB, r migrate.Repair) error {
+	confirm := func(msg func()) bool {
+		return askConfirm(markYes, msg)
+	}
+	ok, err := repairHistory(db, targetDir, r, markDryRun, confirm)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		os.Exit(1)
+	}
+	return nil
+}
+
+func repairHistory(db *sqlx.DB, dir string, r migrate.Repair, dryRun bool, confirm func(func()) bool) (bool, error) {
+	m := newMigrator(db, dir)
+	m.NoLock = markNoLock
+	m.LockTimeout = markLockTimeout
+
+	plan, err := m.PlanRepair(context.Background(), r)
+	if err != nil {
+		return false, err
+	}
+	if len(plan.Stmts) == 0 {
+		info("Nothing to do.")
+		return true, nil
+	}
+	if dryRun {
+		for _, s := range plan.Stmts {
+			fmt.Println(s)
+		}
+		return true, nil
+	}
+
+	abort := !confirm(func() {
+		info("The following statements will be executed on _migrations table:")
+		for _, s := range plan.Stmts {
+			info(" ", s)
+		}
+	})
+	if abort {
+		info("Abort.")
+		return false, nil
+	}
+
+	err = m.ApplyRepair(context.Background(), plan)
+	return err == nil, err
+}
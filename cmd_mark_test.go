@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/makiuchi-d/migy/internal/testutil"
+	"github.com/makiuchi-d/migy/migrate"
+	"github.com/makiuchi-d/migy/migrations"
+	"github.com/makiuchi-d/migy/sqlfile"
+)
+
+func TestRepairHistory(t *testing.T) {
+	dir := filepath.Join("testdata", "snapshot")
+	yes := func(func()) bool { return true }
+	no := func(func()) bool { return false }
+	mark := migrate.Repair{Mark: []int{20}}
+	unmark := migrate.Repair{Unmark: []int{20}}
+
+	tests := map[string]struct {
+		repair  migrate.Repair
+		dryRun  bool
+		confirm func(func()) bool
+		ok      bool
+		cur     int
+		err     error
+	}{
+		"mark": {
+			repair:  mark,
+			confirm: yes,
+			ok:      true,
+			cur:     20,
+		},
+		"dry-run": {
+			repair:  mark,
+			dryRun:  true,
+			confirm: yes,
+			ok:      true,
+			cur:     10,
+		},
+		"abort": {
+			repair:  mark,
+			confirm: no,
+			ok:      false,
+			cur:     10,
+		},
+		"changed": {
+			repair:  migrate.Repair{Mark: []int{20}, FixTitles: true},
+			confirm: nil, // changes the title of 10 while confirming
+			ok:      false,
+			cur:     10,
+			err:     migrate.ErrPlanChanged,
+		},
+		"unmark": {
+			repair:  unmark,
+			confirm: yes,
+			ok:      true,
+			cur:     10,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			db := sqlx.NewDb(testutil.NewDB("db"), "mysql")
+			defer db.Close()
+			files := []string{"000000_init.all.sql", "000010_create_users.up.sql"}
+			if name == "unmark" {
+				files = append(files, "000020_alter_users.up.sql")
+			}
+			for _, f := range files {
+				if err := sqlfile.Apply(db, filepath.Join(dir, f)); err != nil {
+					t.Fatalf("apply %v: %v", f, err)
+				}
+			}
+
+			confirm := test.confirm
+			if confirm == nil {
+				confirm = func(func()) bool {
+					if _, err := db.Exec("UPDATE _migrations SET title = 'changed' WHERE id = 10"); err != nil {
+						t.Fatal(err)
+					}
+					return true
+				}
+			}
+
+			ok, err := repairHistory(db, dir, test.repair, test.dryRun, confirm)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("err = %v, wants %v", err, test.err)
+			}
+			if ok != test.ok {
+				t.Fatalf("ok = %v, wants %v", ok, test.ok)
+			}
+
+			hists, err := migrations.LoadHistories(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cur := hists.CurrentNum(); cur != test.cur {
+				t.Errorf("current = %v, wants %v", cur, test.cur)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/spf13/cobra"
+)
+
+var cmdRedo = &cobra.Command{
+	Use:   "redo [flags] [--host HOST DB_NAME | --dsn DSN]",
+	Short: "Roll back the latest migrations and apply them again",
+	Long: `Roll back the latest migrations and apply them again.
+Applies the down files of the latest --steps migrations and then their up files,
+which is useful while writing a migration.
+The latest are counted in order of application in the _migrations table.
+Refuses to run if the down files have been modified since they were applied,
+unless --force is given.
+This command requires a live database connection.`,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openDB(args)
+		if err != nil {
+			return err
+		}
+
+		confirm := func(msg func()) bool {
+			return askConfirm(redoYes, msg)
+		}
+
+		ok, err := redoMigrations(db, targetDir, redoSteps, redoForce, confirm)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			os.Exit(1)
+		}
+		return nil
+	},
+}
+
+var (
+	redoSteps       int
+	redoYes         bool
+	redoForce       bool
+	redoAssert      bool
+	redoTx          bool
+	redoNoLock      bool
+	redoLockTimeout time.Duration
+)
+
+func init() {
+	cmd.AddCommand(cmdRedo)
+	addFlagsForDB(cmdRedo)
+	cmdRedo.Flags().IntVarP(&redoSteps, "steps", "s", 1, "number of the latest migrations to redo")
+	cmdRedo.Flags().BoolVarP(&redoYes, "yes", "y", false, "assume \"yes\" as answer to all prompts")
+	cmdRedo.Flags().BoolVarP(&redoForce, "force", "f", false, "redo even if the down files have been modified")
+	cmdRedo.Flags().BoolVarP(&redoAssert, "assert", "", false, "run the migy:assert annotations after each file")
+	cmdRedo.Flags().BoolVarP(&redoTx, "tx", "", false, "apply each up/down file in a transaction")
+	cmdRedo.Flags().BoolVarP(&redoNoLock, "no-lock", "", false, "do not take the migration lock on the database")
+	cmdRedo.Flags().DurationVarP(&redoLockTimeout, "lock-timeout", "", 10*time.Second, "time to wait for the migration lock (negative waits forever)")
+}
+
+func redoMigrations(db *sqlx.DB, dir string, steps int, force bool, confirm func(func()) bool) (bool, error) {
+	ctx := context.Background()
+	m := newMigrator(db, dir)
+	m.Force = force
+	m.Assert = redoAssert
+	m.Tx = redoTx
+	m.NoLock = redoNoLock
+	m.LockTimeout = redoLockTimeout
+
+	plan, err := m.RedoPlan(ctx, steps)
+	if err != nil {
+		return false, err
+	}
+	if err := plan.ModifiedError(); err != nil && !force {
+		return false, err
+	}
+
+	abort := !confirm(func() {
+		info("The following migration files will be applied:")
+		for _, file := range plan.Files() {
+			info(" -", file)
+		}
+	})
+	if abort {
+		info("Abort.")
+		return false, nil
+	}
+
+	_, err = m.Apply(ctx, plan)
+	return err == nil, err
+}
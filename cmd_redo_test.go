@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/makiuchi-d/migy/internal/testutil"
+	"github.com/makiuchi-d/migy/migrations"
+)
+
+func TestRedoMigrations(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.CopyFS(dir, os.DirFS(filepath.Join("testdata", "apply"))); err != nil {
+		t.Fatal(err)
+	}
+
+	db := sqlx.NewDb(testutil.NewDB("db"), "mysql")
+	yes := func(func()) bool { return true }
+	no := func(func()) bool { return false }
+
+	if _, err := applyMigrations(db, dir, -1, false, false, yes); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+
+	ok, err := redoMigrations(db, dir, 1, false, no)
+	if err != nil || ok {
+		t.Fatalf("redo must be aborted: %v, %v", ok, err)
+	}
+
+	ok, err = redoMigrations(db, dir, 2, false, yes)
+	if err != nil || !ok {
+		t.Fatalf("redo 2: %v, %v", ok, err)
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, "000030_third.down.sql"), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("SELECT 1;\n")
+	f.Close()
+
+	_, err = redoMigrations(db, dir, 1, false, yes)
+	if err == nil || !strings.Contains(err.Error(), "000030_third") {
+		t.Fatalf("redo must fail with modified down file: %v", err)
+	}
+
+	ok, err = redoMigrations(db, dir, 1, true, yes)
+	if err != nil || !ok {
+		t.Fatalf("redo with force: %v, %v", ok, err)
+	}
+
+	hs, err := migrations.LoadHistories(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if num := hs.CurrentNum(); num != 30 {
+		t.Errorf("current = %v, wants 30", num)
+	}
+}
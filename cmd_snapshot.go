@@ -5,8 +5,6 @@ import (
 	"os"
 	"path/filepath"
 
-	"github.com/jmoiron/sqlx"
-	"github.com/makiuchi-d/testdb"
 	"github.com/spf13/cobra"
 
 	"github.com/makiuchi-d/migy/migrations"
@@ -62,14 +60,12 @@ func snapshotToSQLFile(dir string, num int, overwrite bool) error {
 		return err
 	}
 
-	db := sqlx.NewDb(testdb.New("db"), "mysql")
-
-	for _, file := range files {
-		info("applying:", file)
-		if err := sqlfile.Apply(db, filepath.Join(dir, file)); err != nil {
-			return err
-		}
+	db, err := newMigrator(nil, dir).OpenSandbox(files)
+	if err != nil {
+		return err
 	}
+	defer db.Close()
+
 	info("applying:", mig.UpName())
 	if err := sqlfile.Apply(db, filepath.Join(dir, mig.UpName())); err != nil {
 		return err
@@ -1,13 +1,12 @@
 package main
 
 import (
-	"errors"
+	"context"
 	"fmt"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/spf13/cobra"
 
-	"github.com/makiuchi-d/migy/dbstate"
 	"github.com/makiuchi-d/migy/migrations"
 )
 
@@ -40,24 +39,13 @@ func init() {
 }
 
 func readStatus(db *sqlx.DB, dir string) (string, error) {
-	var hists []migrations.History
-	if db != nil {
-		err := dbstate.HasMigrationTable(db)
-		if !errors.Is(err, dbstate.ErrNoMigrationTable) {
-			hists, err = migrations.LoadHistories(db)
-			if err != nil {
-				return "", err
-			}
-		}
-	}
-
-	migs, err := migrations.Load(dir)
+	sts, err := newMigrator(db, dir).Status(context.Background())
 	if err != nil {
 		return "", err
 	}
 
 	var b []byte
-	for st := range migrations.BuildStatus(migs, hists) {
+	for _, st := range sts {
 		b = append(formatStatus(b, st), '\n')
 	}
 
@@ -90,5 +78,12 @@ func formatStatus(b []byte, st migrations.Status) []byte {
 		b = fmt.Appendf(b, "⚠%q DB:%q", st.Title, st.DBTitle)
 	}
 
+	if st.Modified {
+		b = fmt.Append(b, " ⚠modified")
+	}
+	if st.OutOfOrder {
+		b = fmt.Append(b, " ⚠out-of-order")
+	}
+
 	return b
 }
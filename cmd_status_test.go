@@ -112,6 +112,21 @@ func TestFormatStatus(t *testing.T) {
 			},
 			exp: "000020\t⏫⏬⏺\t✅2025-09-05 20:30:40\t⚠\"second\" DB:\"db-second\"",
 		},
+		"modified": {
+			st: migrations.Status{
+				Migration: newMig(30, "third", true, false),
+				Applied:   time.Date(2025, 9, 6, 1, 2, 3, 0, time.UTC),
+				Modified:  true,
+			},
+			exp: "000030\t⏫⏬　\t✅2025-09-06 01:02:03\t\"third\" ⚠modified",
+		},
+		"out-of-order": {
+			st: migrations.Status{
+				Migration:  newMig(40, "fourth", true, false),
+				OutOfOrder: true,
+			},
+			exp: "000040\t⏫⏬　\t　0000-00-00 --:--:--\t\"fourth\" ⚠out-of-order",
+		},
 	}
 
 	for name, test := range tests {
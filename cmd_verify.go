@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/spf13/cobra"
+
+	"github.com/makiuchi-d/migy/dbstate"
+	"github.com/makiuchi-d/migy/migrations"
+)
+
+var cmdVerify = &cobra.Command{
+	Use:   "verify [flags] [DUMP_FILE | --host HOST DB_NAME | --dsn DSN]",
+	Short: "Detect drift between the database and its migration history",
+	Long: `Detect drift between the database and its migration history.
+Rebuilds the migration number recorded in the database's _migrations table
+in a temporary database and compares the schemas of both.
+Records are compared only for the tables specified by --data.
+The _migrations and _migrations_progress tables and the _migration_exists
+procedure used by migy are not compared.`,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openDBorDumpfile(args)
+		if err != nil {
+			return err
+		}
+		if db == nil {
+			return errors.New("data source or dump file is required")
+		}
+
+		diff, err := verifyDatabase(db, targetDir, verifyData)
+		if err != nil {
+			return err
+		}
+
+		if diff != "" {
+			info(diff, "\nverify failed")
+			os.Exit(1)
+		}
+
+		return nil
+	},
+}
+
+var verifyData []string
+
+func init() {
+	cmd.AddCommand(cmdVerify)
+	addFlagsForDB(cmdVerify)
+	cmdVerify.Flags().StringSliceVarP(&verifyData, "data", "", nil, "tables to compare records (comma separated)")
+}
+
+// verifyDatabase compares db with the state rebuilt from the migration files
+// up to the current number of db.
+func verifyDatabase(db *sqlx.DB, dir string, tables []string) (string, error) {
+	if err := dbstate.HasMigrationTable(db); err != nil {
+		return "", err
+	}
+	hists, err := migrations.LoadHistories(db)
+	if err != nil {
+		return "", err
+	}
+	cur := hists.CurrentNum()
+	if cur < 0 {
+		return "", errors.New("'_migrations' table found but not initialized")
+	}
+
+	info(fmt.Sprintf("checking %06d...", cur))
+	diff, err := newMigrator(db, dir).Verify(context.Background(), cur, tables)
+	if err != nil {
+		return "", err
+	}
+	if diff != "" {
+		return diff, nil
+	}
+	info("ok")
+
+	return "", nil
+}
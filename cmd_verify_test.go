@@ -0,0 +1,88 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jmoiron/sqlx"
+	"github.com/makiuchi-d/testdb"
+
+	"github.com/makiuchi-d/migy/sqlfile"
+)
+
+func TestVerifyDatabase(t *testing.T) {
+	dir := filepath.Join("testdata", "snapshot")
+
+	tests := map[string]struct {
+		files  []string
+		sqls   []string
+		tables []string
+		diff   string
+	}{
+		"no-drift": {
+			files: []string{"000000_init.all.sql", "000010_create_users.up.sql", "000020_alter_users.up.sql"},
+			diff:  "",
+		},
+		"no-drift-10": {
+			files:  []string{"000000_init.all.sql", "000010_create_users.up.sql"},
+			tables: []string{"_migrations", "users"},
+			diff:   "",
+		},
+		"schema": {
+			files: []string{"000000_init.all.sql", "000010_create_users.up.sql"},
+			sqls:  []string{"ALTER TABLE users ADD COLUMN age int"},
+			diff:  "column users.age added: int",
+		},
+		"table": {
+			files: []string{"000000_init.all.sql", "000010_create_users.up.sql"},
+			sqls:  []string{"CREATE TABLE hotfix (id int PRIMARY KEY)"},
+			diff:  "unexpected \"hotfix\" table found",
+		},
+		"bookkeeping": {
+			files: []string{"000000_init.all.sql", "000010_create_users.up.sql"},
+			sqls: []string{
+				"ALTER TABLE _migrations ADD COLUMN up_checksum CHAR(64), ADD COLUMN down_checksum CHAR(64)",
+				"CREATE TABLE _migrations_progress (id INTEGER PRIMARY KEY, file VARCHAR(255))",
+			},
+			tables: []string{"_migrations"},
+			diff:   "",
+		},
+		"records-ignored": {
+			files: []string{"000000_init.all.sql", "000010_create_users.up.sql"},
+			sqls:  []string{"INSERT INTO users (id, name) VALUES (2, 'user2')"},
+			diff:  "",
+		},
+		"records": {
+			files:  []string{"000000_init.all.sql", "000010_create_users.up.sql"},
+			sqls:   []string{"INSERT INTO users (id, name) VALUES (2, 'user2')"},
+			tables: []string{"users"},
+			diff:   "users[id=2] added: (2, 'user2')",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			db := sqlx.NewDb(testdb.New("db"), "mysql")
+			defer db.Close()
+			for _, f := range test.files {
+				if err := sqlfile.Apply(db, filepath.Join(dir, f)); err != nil {
+					t.Fatalf("apply %v: %v", f, err)
+				}
+			}
+			for _, q := range test.sqls {
+				if _, err := db.Exec(q); err != nil {
+					t.Fatalf("exec %v: %v", q, err)
+				}
+			}
+
+			diff, err := verifyDatabase(db, dir, test.tables)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := cmp.Diff(test.diff, diff); d != "" {
+				t.Error(d)
+			}
+		})
+	}
+}
@@ -12,6 +12,8 @@ var cmdVersion = &cobra.Command{
 	Short: "Show version",
 	Long:  `Show version`,
 
+	Annotations: map[string]string{noConfig: ""},
+
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return showVersion()
 	},
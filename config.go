@@ -0,0 +1,214 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// configFiles are the configuration files searched in the working directory in order.
+var configFiles = []string{"migy.yaml", "migy.yml", "migy.toml"}
+
+// config is the settings in the configuration file.
+// The keys of the settings are the long names of the flags.
+type config struct {
+	path     string
+	settings map[string]any            // settings for all environments
+	envs     map[string]map[string]any // settings of each environment
+}
+
+// connectionSettings are the settings to connect the database.
+// They are used together, so that the connection given by the flags or the dump file is not mixed with them.
+var connectionSettings = []string{"host", "port", "user", "password", "dsn"}
+
+// loadConfigFlags sets the flags of the command from the configuration file.
+func loadConfigFlags(c *cobra.Command, args []string, path, env string) error {
+	if path == "" {
+		p, err := findConfig(".")
+		if err != nil {
+			return err
+		}
+		path = p
+	}
+	if path == "" {
+		if env != "" {
+			return errors.New("--env requires a configuration file (" + strings.Join(configFiles, ", ") + ")")
+		}
+		return nil
+	}
+
+	conf, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+	settings, err := conf.environment(env)
+	if err != nil {
+		return err
+	}
+
+	fs := c.Flags()
+	explicit := fs.Changed("host") || fs.Changed("dsn")
+	dumpfile := len(args) > 0 && settings["host"] == "" && strings.Contains(c.Use, "DUMP_FILE")
+	if explicit || dumpfile {
+		for _, k := range connectionSettings {
+			delete(settings, k)
+		}
+	}
+	return applyConfig(c, settings, filepath.Dir(path))
+}
+
+// findConfig returns the path of the configuration file in the dir, or empty if not found.
+func findConfig(dir string) (string, error) {
+	for _, name := range configFiles {
+		p := filepath.Join(dir, name)
+		_, err := os.Stat(p)
+		if err == nil {
+			return p, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+// loadConfig reads the YAML or TOML configuration file.
+func loadConfig(path string) (*config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]any
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &raw)
+	case ".toml":
+		err = toml.Unmarshal(b, &raw)
+	default:
+		return nil, fmt.Errorf("%s: unknown configuration format", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	conf := &config{path: path, settings: raw, envs: map[string]map[string]any{}}
+	if e, ok := raw["environments"]; ok {
+		envs, ok := e.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s: environments must be a map", path)
+		}
+		for name, v := range envs {
+			s, ok := v.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%s: environment %q must be a map", path, name)
+			}
+			conf.envs[name] = s
+		}
+		delete(raw, "environments")
+	}
+	// --force of apply and redo skips the check of the modified files, but of init, create and snapshot overwrites the file
+	if _, ok := raw["force"]; ok {
+		return nil, fmt.Errorf("%s: force is not allowed for all environments, set it in an environment", path)
+	}
+	return conf, nil
+}
+
+// environment returns the settings of the environment merged over the settings for all environments.
+// The empty name returns the settings for all environments.
+func (c *config) environment(name string) (map[string]string, error) {
+	settings := make(map[string]string)
+	add := func(s map[string]any) error {
+		for k, v := range s {
+			str, err := configValue(v)
+			if err != nil {
+				return fmt.Errorf("%s: %s: %w", c.path, k, err)
+			}
+			settings[strings.ReplaceAll(k, "_", "-")] = str
+		}
+		return nil
+	}
+
+	if err := add(c.settings); err != nil {
+		return nil, err
+	}
+	if name != "" {
+		env, ok := c.envs[name]
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown environment %q", c.path, name)
+		}
+		if err := add(env); err != nil {
+			return nil, err
+		}
+	}
+	return settings, nil
+}
+
+// configValue returns the value in the form of the flag argument.
+func configValue(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int, int64, float64:
+		return fmt.Sprint(v), nil
+	case []any:
+		ss := make([]string, len(v))
+		for i, e := range v {
+			s, err := configValue(e)
+			if err != nil {
+				return "", err
+			}
+			ss[i] = s
+		}
+		return strings.Join(ss, ","), nil
+	}
+	return "", fmt.Errorf("unsupported value: %v", v)
+}
+
+// applyConfig sets the flags of the command which are not given explicitly.
+// The settings for the flags of the other commands are ignored.
+// The relative dir is resolved from confDir.
+func applyConfig(c *cobra.Command, settings map[string]string, confDir string) error {
+	known := flagNames(c.Root())
+	for _, k := range slices.Sorted(maps.Keys(settings)) {
+		if k == "config" || k == "env" || !slices.Contains(known, k) {
+			return fmt.Errorf("unknown setting %q in the configuration file", k)
+		}
+		f := c.Flags().Lookup(k)
+		if f == nil || f.Changed {
+			continue
+		}
+		v := settings[k]
+		if k == "dir" && !filepath.IsAbs(v) {
+			v = filepath.Join(confDir, v)
+		}
+		if err := f.Value.Set(v); err != nil {
+			return fmt.Errorf("invalid setting %q in the configuration file: %w", k, err)
+		}
+	}
+	return nil
+}
+
+// flagNames returns the names of the flags of the command and its subcommands.
+func flagNames(c *cobra.Command) []string {
+	var names []string
+	visit := func(f *pflag.Flag) { names = append(names, f.Name) }
+	c.PersistentFlags().VisitAll(visit)
+	c.Flags().VisitAll(visit)
+	for _, sub := range c.Commands() {
+		names = append(names, flagNames(sub)...)
+	}
+	return names
+}
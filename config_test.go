@@ -0,0 +1,139 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/cobra"
+)
+
+type testFlags struct {
+	Dir         string
+	DSN         string
+	Host        string
+	User        string
+	LockTimeout time.Duration
+	Tx          bool
+	Ignores     []string
+}
+
+// newTestCommand returns a command with the flags like apply and its root.
+func newTestCommand(f *testFlags) *cobra.Command {
+	root := &cobra.Command{Use: "migy"}
+	root.PersistentFlags().StringVarP(&f.Dir, "dir", "d", ".", "")
+	c := &cobra.Command{Use: "apply [flags] [--host HOST DB_NAME | --dsn DSN]", RunE: func(*cobra.Command, []string) error { return nil }}
+	c.Flags().StringVar(&f.DSN, "dsn", "", "")
+	c.Flags().StringVarP(&f.Host, "host", "h", "", "")
+	c.Flags().StringVarP(&f.User, "user", "u", "", "")
+	c.Flags().DurationVar(&f.LockTimeout, "lock-timeout", 10*time.Second, "")
+	c.Flags().BoolVar(&f.Tx, "tx", false, "")
+	c.Flags().StringSliceVar(&f.Ignores, "ignore", nil, "")
+	root.AddCommand(c)
+	return c
+}
+
+func TestLoadConfigFlags(t *testing.T) {
+	dir := filepath.Join("testdata", "config")
+	migdir := filepath.Join(dir, "migrations")
+
+	tests := map[string]struct {
+		env   string
+		flags []string
+		exp   testFlags
+	}{
+		"default": {
+			exp: testFlags{Dir: migdir, LockTimeout: 30 * time.Second},
+		},
+		"staging": {
+			env: "staging",
+			exp: testFlags{
+				Dir:         migdir,
+				DSN:         "user:pass@tcp(staging:3306)/app",
+				LockTimeout: 30 * time.Second,
+				Tx:          true,
+				Ignores:     []string{"users.updated_at", "posts.updated_at"},
+			},
+		},
+		"production": {
+			env: "production",
+			exp: testFlags{Dir: migdir, Host: "db.example.com", User: "migy", LockTimeout: time.Minute},
+		},
+		"explicit flags": {
+			env:   "staging",
+			flags: []string{"--dir", "other", "--lock-timeout", "5s", "--ignore", "a.b"},
+			exp: testFlags{
+				Dir:         "other",
+				DSN:         "user:pass@tcp(staging:3306)/app",
+				LockTimeout: 5 * time.Second,
+				Tx:          true,
+				Ignores:     []string{"a.b"},
+			},
+		},
+		"explicit connection": {
+			env:   "production",
+			flags: []string{"--dsn", "root@tcp(localhost:3306)/app"},
+			exp:   testFlags{Dir: migdir, DSN: "root@tcp(localhost:3306)/app", LockTimeout: time.Minute},
+		},
+	}
+
+	for _, file := range []string{"migy.yaml", "migy.toml"} {
+		for name, test := range tests {
+			t.Run(file+"/"+name, func(t *testing.T) {
+				var f testFlags
+				c := newTestCommand(&f)
+				if err := c.ParseFlags(test.flags); err != nil {
+					t.Fatal(err)
+				}
+				err := loadConfigFlags(c, c.Flags().Args(), filepath.Join(dir, file), test.env)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if d := cmp.Diff(test.exp, f); d != "" {
+					t.Error(d)
+				}
+			})
+		}
+	}
+}
+
+func TestLoadConfigFlagsError(t *testing.T) {
+	dir := filepath.Join("testdata", "config")
+
+	tests := map[string]struct {
+		path string
+		env  string
+	}{
+		"unknown env":       {filepath.Join(dir, "migy.yaml"), "dev"},
+		"env without file":  {"", "staging"},
+		"missing file":      {filepath.Join(dir, "missing.yaml"), ""},
+		"unknown setting":   {filepath.Join(dir, "unknown.yaml"), ""},
+		"unknown extension": {filepath.Join(dir, "migy.json"), ""},
+		"force for all":     {filepath.Join(dir, "force.yaml"), ""},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var f testFlags
+			c := newTestCommand(&f)
+			if err := loadConfigFlags(c, nil, test.path, test.env); err == nil {
+				t.Fatal("must be error")
+			}
+		})
+	}
+}
+
+func TestNoConfigCommands(t *testing.T) {
+	defer func(p string) { configPath = p }(configPath)
+	configPath = filepath.Join("testdata", "config", "unknown.yaml")
+
+	for _, c := range []*cobra.Command{cmdVersion, cmdInit} {
+		if err := cmd.PersistentPreRunE(c, nil); err != nil {
+			t.Errorf("%s: %v", c.Name(), err)
+		}
+	}
+	if err := cmd.PersistentPreRunE(cmdStatus, nil); err == nil {
+		t.Errorf("status must load the configuration file")
+	}
+}
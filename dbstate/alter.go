@@ -0,0 +1,220 @@
+package dbstate
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// AlterStatements returns the SQL statements to change the schema of the from tables into the to tables.
+// The tables must be ordered by their references as returned by GetTables.
+func AlterStatements(from, to []*Table) ([]string, error) {
+	fdefs, err := parseTables(from)
+	if err != nil {
+		return nil, err
+	}
+	tdefs, err := parseTables(to)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string][]SchemaChange)
+	for _, t := range to {
+		if fd, ok := fdefs[t.Name]; ok {
+			changes[t.Name] = DiffTableDefs(fd, tdefs[t.Name])
+		}
+	}
+
+	var stmts []string
+	alter := func(table, format string, a ...any) {
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ", quoteName(table))+fmt.Sprintf(format, a...))
+	}
+
+	// drop the foreign keys first not to prevent dropping tables and columns
+	for _, t := range to {
+		for _, c := range changes[t.Name] {
+			if c.Object == "foreign key" && c.Action != Added {
+				alter(t.Name, "DROP FOREIGN KEY %s", quoteName(c.Name))
+			}
+		}
+	}
+
+	for _, t := range slices.Backward(from) {
+		if _, ok := tdefs[t.Name]; !ok {
+			stmts = append(stmts, "DROP TABLE "+quoteName(t.Name))
+		}
+	}
+
+	for _, t := range to {
+		if _, ok := fdefs[t.Name]; !ok {
+			stmts = append(stmts, reAutoIncrement.ReplaceAllString(t.Create, ""))
+		}
+	}
+
+	for _, t := range to {
+		fd, ok := fdefs[t.Name]
+		if !ok {
+			continue
+		}
+		td := tdefs[t.Name]
+		chs := changes[t.Name]
+
+		// indexes and checks to drop, before the columns they refer to
+		for _, c := range chs {
+			switch {
+			case c.Object == "index" && c.Action == Renamed:
+				alter(t.Name, "RENAME INDEX %s TO %s", quoteName(c.Name), quoteName(c.After))
+			case c.Object == "index" && c.Name == "PRIMARY" && c.Action != Added:
+				alter(t.Name, "DROP PRIMARY KEY")
+			case c.Object == "index" && c.Action != Added:
+				alter(t.Name, "DROP INDEX %s", quoteName(c.Name))
+			case c.Object == "check" && c.Action != Added:
+				alter(t.Name, "DROP CHECK %s", quoteName(c.Name))
+			}
+		}
+
+		// columns
+		for _, c := range chs {
+			if c.Object == "column" && c.Action == Removed {
+				alter(t.Name, "DROP COLUMN %s", quoteName(c.Name))
+			}
+		}
+		moves := reorderColumns(fd, td)
+		modified := make(map[string]bool)
+		for _, m := range moves {
+			modified[m.col.Name] = true
+		}
+		for _, c := range chs {
+			if c.Object == "column" && c.Action == Modified && !modified[c.Name] {
+				modified[c.Name] = true
+				col := td.Columns[slices.IndexFunc(td.Columns, func(col Column) bool { return col.Name == c.Name })]
+				alter(t.Name, "MODIFY COLUMN %s %s", quoteName(col.Name), columnDef(col))
+			}
+		}
+		for _, m := range moves {
+			alter(t.Name, "MODIFY COLUMN %s %s %s", quoteName(m.col.Name), columnDef(m.col), m.pos)
+		}
+		for _, c := range chs {
+			if c.Object == "column" && c.Action == Added {
+				alter(t.Name, "ADD COLUMN %s %s %s", quoteName(c.Name), c.After, columnPosition(td, c.Name))
+			}
+		}
+
+		// indexes and checks to add
+		for _, idx := range td.Indexes {
+			if !slices.ContainsFunc(chs, func(c SchemaChange) bool {
+				return c.Object == "index" && c.Name == idx.Name && (c.Action == Added || c.Action == Modified)
+			}) {
+				continue
+			}
+			if idx.Name == "PRIMARY" {
+				alter(t.Name, "ADD PRIMARY KEY %s", idx.Definition)
+			} else {
+				alter(t.Name, "ADD %s %s %s", idx.Kind, quoteName(idx.Name), idx.Definition)
+			}
+		}
+		for _, c := range chs {
+			if c.Object != "check" {
+				continue
+			}
+			switch c.Action {
+			case Added, Modified:
+				alter(t.Name, "ADD CONSTRAINT %s %s", quoteName(c.Name), c.After)
+			case Renamed:
+				alter(t.Name, "ADD CONSTRAINT %s %s", quoteName(c.After), c.Before)
+			}
+		}
+
+		// table options
+		for _, c := range chs {
+			if c.Object != "option" {
+				continue
+			}
+			switch {
+			case c.Action != Removed:
+				alter(t.Name, "%s=%s", c.Name, c.After)
+			case c.Name == "COMMENT":
+				alter(t.Name, "COMMENT=''")
+			}
+		}
+	}
+
+	for _, t := range to {
+		for _, c := range changes[t.Name] {
+			if c.Object != "foreign key" {
+				continue
+			}
+			switch c.Action {
+			case Added, Modified:
+				alter(t.Name, "ADD CONSTRAINT %s %s", quoteName(c.Name), c.After)
+			case Renamed:
+				alter(t.Name, "ADD CONSTRAINT %s %s", quoteName(c.After), c.Before)
+			}
+		}
+	}
+
+	return stmts, nil
+}
+
+func parseTables(tbls []*Table) (map[string]*TableDef, error) {
+	defs := make(map[string]*TableDef, len(tbls))
+	for _, t := range tbls {
+		td, err := ParseCreateTable(t.Create)
+		if err != nil {
+			return nil, fmt.Errorf("table %s: %w", t.Name, err)
+		}
+		defs[t.Name] = td
+	}
+	return defs, nil
+}
+
+func quoteName(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+type columnMove struct {
+	col Column
+	pos string
+}
+
+// reorderColumns returns the moves of the columns to change the column order of from into to.
+// The columns only in from or to are not counted.
+func reorderColumns(from, to *TableDef) []columnMove {
+	var cur, exp []string
+	for _, c := range from.Columns {
+		if slices.ContainsFunc(to.Columns, func(tc Column) bool { return tc.Name == c.Name }) {
+			cur = append(cur, c.Name)
+		}
+	}
+	var cols []Column
+	for _, c := range to.Columns {
+		if slices.Contains(cur, c.Name) {
+			exp = append(exp, c.Name)
+			cols = append(cols, c)
+		}
+	}
+
+	var moves []columnMove
+	for i, name := range exp {
+		if cur[i] == name {
+			continue
+		}
+		j := slices.Index(cur, name)
+		cur = slices.Insert(slices.Delete(cur, j, j+1), i, name)
+		pos := "FIRST"
+		if i > 0 {
+			pos = "AFTER " + quoteName(exp[i-1])
+		}
+		moves = append(moves, columnMove{cols[i], pos})
+	}
+	return moves
+}
+
+// columnPosition returns "FIRST" or "AFTER `prev`" for the column in the table.
+func columnPosition(td *TableDef, name string) string {
+	i := slices.IndexFunc(td.Columns, func(c Column) bool { return c.Name == name })
+	if i <= 0 {
+		return "FIRST"
+	}
+	return "AFTER " + quoteName(td.Columns[i-1].Name)
+}
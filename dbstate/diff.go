@@ -3,6 +3,7 @@ package dbstate
 import (
 	"fmt"
 	"iter"
+	"regexp"
 	"slices"
 	"strings"
 
@@ -10,70 +11,210 @@ import (
 	"github.com/makiuchi-d/anydiff"
 )
 
-func Diff(db *sqlx.DB, ss *Snapshot, ignores map[string][]string) (string, error) {
+type DiffKind int
+
+const (
+	UnexpectedTable DiffKind = iota
+	MissingTable
+	TableChanged
+	RecordsChanged
+	UnexpectedProcedure
+	MissingProcedure
+	ProcedureChanged
+)
+
+// Difference is a difference between a snapshot and a database.
+type Difference struct {
+	Kind    DiffKind
+	Name    string         // table or stored procedure name
+	Changes []SchemaChange // semantic changes of TableChanged
+	Rows    []RowChange    // changes of RecordsChanged in the table with primary key
+	Text    string         // line diff of RecordsChanged, ProcedureChanged and unparsable TableChanged
+}
+
+// RowChange is a difference of a record identified by its primary key.
+type RowChange struct {
+	Table  string
+	Key    string // e.g. "id=3"
+	Action Action // Added, Removed or Modified
+	Column string // modified column
+	Before string
+	After  string
+}
+
+func (c RowChange) String() string {
+	switch c.Action {
+	case Added:
+		return fmt.Sprintf("%s[%s] added: %s", c.Table, c.Key, c.After)
+	case Removed:
+		return fmt.Sprintf("%s[%s] removed: %s", c.Table, c.Key, c.Before)
+	}
+	return fmt.Sprintf("%s[%s].%s: %s -> %s", c.Table, c.Key, c.Column, c.Before, c.After)
+}
+
+func (d Difference) String() string {
+	switch d.Kind {
+	case UnexpectedTable:
+		return fmt.Sprintf("unexpected %q table found\n", d.Name)
+	case MissingTable:
+		return fmt.Sprintf("missing %q table\n", d.Name)
+	case TableChanged:
+		if d.Changes == nil {
+			return fmt.Sprintf("create table %q differs:\n%s", d.Name, d.Text)
+		}
+		var sb strings.Builder
+		for _, c := range d.Changes {
+			sb.WriteString(c.String())
+			sb.WriteByte('\n')
+		}
+		return sb.String()
+	case RecordsChanged:
+		if d.Rows == nil {
+			return fmt.Sprintf("records in %q differs:\n%s", d.Name, d.Text)
+		}
+		var sb strings.Builder
+		for _, r := range d.Rows {
+			sb.WriteString(r.String())
+			sb.WriteByte('\n')
+		}
+		return sb.String()
+	case UnexpectedProcedure:
+		return fmt.Sprintf("unexpected %q stored procedure found\n", d.Name)
+	case MissingProcedure:
+		return fmt.Sprintf("missing %q stored procedure\n", d.Name)
+	case ProcedureChanged:
+		return fmt.Sprintf("stored procedure %q differs:\n%s", d.Name, d.Text)
+	}
+	return ""
+}
+
+type Differences []Difference
+
+func (ds Differences) String() string {
 	var sb strings.Builder
+	for _, d := range ds {
+		sb.WriteString(d.String())
+	}
+	return sb.String()
+}
 
-	if err := diffTables(&sb, db, ss, ignores); err != nil {
-		return "", err
+// Without returns the differences except those of the tables or stored procedures of the names.
+func (ds Differences) Without(names ...string) Differences {
+	return slices.DeleteFunc(slices.Clone(ds), func(d Difference) bool {
+		return slices.Contains(names, d.Name)
+	})
+}
+
+// Compare returns the differences of db from the snapshot.
+func Compare(db *sqlx.DB, ss *Snapshot, ignores map[string][]string) (Differences, error) {
+	ds, err := diffTables(db, ss, ignores)
+	if err != nil {
+		return nil, err
 	}
-	if err := diffProcedures(&sb, db, ss); err != nil {
-		return "", err
+	pds, err := diffProcedures(db, ss)
+	if err != nil {
+		return nil, err
 	}
+	return append(ds, pds...), nil
+}
 
-	return sb.String(), nil
+// Diff returns the differences of db from the snapshot as a string.
+func Diff(db *sqlx.DB, ss *Snapshot, ignores map[string][]string) (string, error) {
+	ds, err := Compare(db, ss, ignores)
+	if err != nil {
+		return "", err
+	}
+	return ds.String(), nil
 }
 
-func diffTables(sb *strings.Builder, db *sqlx.DB, ss *Snapshot, ignores map[string][]string) error {
+func diffTables(db *sqlx.DB, ss *Snapshot, ignores map[string][]string) (Differences, error) {
 
 	tbls, err := GetTables(db)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	var ds Differences
 	checked := make(map[string]struct{}, len(tbls))
 	for _, tbl := range tbls {
 		checked[tbl.Name] = struct{}{}
 
 		sstbl, ok := ss.Tables[tbl.Name]
 		if !ok {
-			fmt.Fprintf(sb, "unexpected %q table found\n", tbl.Name)
+			ds = append(ds, Difference{Kind: UnexpectedTable, Name: tbl.Name})
 			continue
 		}
-		create := strings.Split(tbl.Create, "\n")
-		expcreate := strings.Split(sstbl.Create, "\n")
 
-		edit := anydiff.Diff(expcreate, create, anydiff.Cmp)
-		if edit.Distance() != 0 {
-			fmt.Fprintf(sb, "create table %q differs:\n", tbl.Name)
-			diffString(sb, edit, expcreate, create)
+		if d, ok := diffCreateTable(sstbl, tbl); ok {
+			ds = append(ds, d)
 			continue
 		}
 
-		err = diffRecords(sb, db, ss, tbl.Name, ignores)
+		d, ok, err := diffRecords(db, ss, tbl.Name, ignores)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		if ok {
+			ds = append(ds, d)
 		}
 	}
 	for name := range ss.Tables {
 		if _, ok := checked[name]; !ok {
-			fmt.Fprintf(sb, "missing %q table\n", name)
+			ds = append(ds, Difference{Kind: MissingTable, Name: name})
+		}
+	}
+
+	return ds, nil
+}
+
+// diffCreateTable compares the table definitions semantically.
+// It falls back to the line diff if the definitions cannot be parsed.
+func diffCreateTable(before, after *Table) (Difference, bool) {
+	bdef, berr := ParseCreateTable(before.Create)
+	adef, aerr := ParseCreateTable(after.Create)
+	if berr == nil && aerr == nil {
+		chs := DiffTableDefs(bdef, adef)
+		if len(chs) == 0 {
+			return Difference{}, false
 		}
+		return Difference{Kind: TableChanged, Name: after.Name, Changes: chs}, true
 	}
 
-	return nil
+	// AUTO_INCREMENT counter is not a part of the schema
+	create := strings.Split(reAutoIncrement.ReplaceAllString(after.Create, ""), "\n")
+	expcreate := strings.Split(reAutoIncrement.ReplaceAllString(before.Create, ""), "\n")
+
+	edit := anydiff.Diff(expcreate, create, anydiff.Cmp)
+	if edit.Distance() == 0 {
+		return Difference{}, false
+	}
+	var sb strings.Builder
+	diffString(&sb, edit, expcreate, create)
+	return Difference{Kind: TableChanged, Name: after.Name, Text: sb.String()}, true
 }
 
-func diffRecords(sb *strings.Builder, db *sqlx.DB, ss *Snapshot, table string, ignores map[string][]string) error {
+var reAutoIncrement = regexp.MustCompile(` AUTO_INCREMENT=[0-9]+`)
+
+func diffRecords(db *sqlx.DB, ss *Snapshot, table string, ignores map[string][]string) (Difference, bool, error) {
 	ign := ignores[table]
 	if slices.Contains(ign, "*") {
 		// ignore all column differences
-		return nil
+		return Difference{}, false, nil
 	}
 	before := ss.Records[table]
 	after, err := GetRecords(db, table)
 	if err != nil {
-		return err
+		return Difference{}, false, err
+	}
+
+	if keys := keyIndexes(ss.Tables[table], after.Columns); keys != nil {
+		rows := diffRowsByKey(table, before, after, keys, ign)
+		if len(rows) == 0 {
+			return Difference{}, false, nil
+		}
+		return Difference{Kind: RecordsChanged, Name: table, Rows: rows}, true, nil
 	}
 
+	// no primary key: compare as a sequence of rows
 	cmp := func(a, b *Row) bool {
 		for i := range len(*a) {
 			if slices.Contains(ign, after.Columns[i]) {
@@ -81,7 +222,7 @@ func diffRecords(sb *strings.Builder, db *sqlx.DB, ss *Snapshot, table string, i
 			}
 			ai := (*a)[i].(*any)
 			bi := (*b)[i].(*any)
-			if *ai != *bi {
+			if normalize(*ai) != normalize(*bi) {
 				return false
 			}
 		}
@@ -90,27 +231,99 @@ func diffRecords(sb *strings.Builder, db *sqlx.DB, ss *Snapshot, table string, i
 
 	edit := anydiff.Diff(before.Rows, after.Rows, cmp)
 	if edit.Distance() == 0 {
+		return Difference{}, false, nil
+	}
+
+	var sb strings.Builder
+	diffString(&sb, edit, before.Rows, after.Rows)
+	return Difference{Kind: RecordsChanged, Name: table, Text: sb.String()}, true, nil
+}
+
+// keyIndexes returns the indexes of the primary key columns, or nil if the table has no primary key.
+func keyIndexes(tbl *Table, columns []string) []int {
+	td, err := ParseCreateTable(tbl.Create)
+	if err != nil {
+		return nil
+	}
+	pk := td.PrimaryKey()
+	if len(pk) == 0 {
 		return nil
 	}
+	keys := make([]int, len(pk))
+	for i, k := range pk {
+		keys[i] = slices.Index(columns, k)
+		if keys[i] < 0 {
+			return nil
+		}
+	}
+	return keys
+}
+
+// diffRowsByKey compares the rows identified by the primary key.
+func diffRowsByKey(table string, before, after *Records, keys []int, ignores []string) []RowChange {
+	rowKey := func(r Row) string {
+		var b []byte
+		for i, k := range keys {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = append(b, after.Columns[k]...)
+			b = append(b, '=')
+			b = appendValue(b, *r[k].(*any))
+		}
+		return string(b)
+	}
 
-	fmt.Fprintf(sb, "records in %q differs:\n", table)
-	diffString(sb, edit, before.Rows, after.Rows)
-	return nil
+	arows := make(map[string]Row, len(after.Rows))
+	for _, r := range after.Rows {
+		arows[rowKey(r)] = r
+	}
+
+	var chs []RowChange
+	brows := make(map[string]struct{}, len(before.Rows))
+	for _, br := range before.Rows {
+		key := rowKey(br)
+		brows[key] = struct{}{}
+		ar, ok := arows[key]
+		if !ok {
+			chs = append(chs, RowChange{Table: table, Key: key, Action: Removed, Before: br.String()})
+			continue
+		}
+		for i, col := range after.Columns {
+			if slices.Contains(ignores, col) {
+				continue
+			}
+			bv, av := *br[i].(*any), *ar[i].(*any)
+			if normalize(bv) != normalize(av) {
+				chs = append(chs, RowChange{Table: table, Key: key, Action: Modified, Column: col,
+					Before: string(appendValue(nil, bv)), After: string(appendValue(nil, av))})
+			}
+		}
+	}
+	for _, ar := range after.Rows {
+		key := rowKey(ar)
+		if _, ok := brows[key]; !ok {
+			chs = append(chs, RowChange{Table: table, Key: key, Action: Added, After: ar.String()})
+		}
+	}
+
+	return chs
 }
 
-func diffProcedures(sb *strings.Builder, db *sqlx.DB, ss *Snapshot) error {
+func diffProcedures(db *sqlx.DB, ss *Snapshot) (Differences, error) {
 	procs, err := GetProcedures(db)
 	if err != nil {
-		return nil
+		return nil, nil
 	}
 
+	var ds Differences
 	checked := make(map[string]struct{}, len(procs))
 	for _, proc := range procs {
 		checked[proc.Name] = struct{}{}
 
 		ssproc, ok := ss.Procedures[proc.Name]
 		if !ok {
-			fmt.Fprintf(sb, "unexpected %q stored procedure found\n", proc.Name)
+			ds = append(ds, Difference{Kind: UnexpectedProcedure, Name: proc.Name})
 			continue
 		}
 		create := strings.Split(proc.Create, "\n")
@@ -121,17 +334,18 @@ func diffProcedures(sb *strings.Builder, db *sqlx.DB, ss *Snapshot) error {
 			continue
 		}
 
-		fmt.Fprintf(sb, "stored procedure %q differs:\n", proc.Name)
-		diffString(sb, edit, expcreate, create)
+		var sb strings.Builder
+		diffString(&sb, edit, expcreate, create)
+		ds = append(ds, Difference{Kind: ProcedureChanged, Name: proc.Name, Text: sb.String()})
 	}
 
 	for name := range ss.Procedures {
 		if _, ok := checked[name]; !ok {
-			fmt.Fprintf(sb, "missing %q stored procedure", name)
+			ds = append(ds, Difference{Kind: MissingProcedure, Name: name})
 		}
 	}
 
-	return nil
+	return ds, nil
 }
 
 func diffString[A, B any](sb *strings.Builder, edit anydiff.Edit, a []A, b []B) {
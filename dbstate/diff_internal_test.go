@@ -73,27 +73,18 @@ CREATE TABLE table3 (
 		}
 	}
 
-	var sb strings.Builder
-	err = diffTables(&sb, db, ss, ignores)
+	ds, err := diffTables(db, ss, ignores)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	exp := "" +
-		"create table \"table1\" differs:\n" +
-		"...\n" +
-		"   `val` int NOT NULL,\n" +
-		"+  `val2` text,\n" +
-		"   PRIMARY KEY (`id`)\n" +
-		"...\n" +
+		"column table1.val2 added: text\n" +
 		"unexpected \"table3\" table found\n" +
-		"records in \"users\" differs:\n" +
-		" (1, 'alice', 30)\n" +
-		"-(2, 'bob', 24)\n" +
-		" (3, 'carol', 28)\n" +
+		"users[id=2] removed: (2, 'bob', 24)\n" +
 		"missing \"table2\" table\n"
 
-	diff := sb.String()
+	diff := ds.String()
 	if diff != exp {
 		t.Errorf("diff:\n%v\nwants:\n%v", diff, exp)
 	}
@@ -139,8 +130,7 @@ END`,
 		}
 	}
 
-	var sb strings.Builder
-	err = diffProcedures(&sb, db, ss)
+	ds, err := diffProcedures(db, ss)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -152,7 +142,7 @@ END`,
 +    SIGNAL SQLSTATE '45001' SET MESSAGE_TEXT = 'proc1';
  END
 `
-	diff := sb.String()
+	diff := ds.String()
 	if diff != exp {
 		t.Errorf("diff:\n%v\nwants:\n%v", diff, exp)
 	}
@@ -266,3 +256,81 @@ func TestDiffString(t *testing.T) {
 		t.Fatalf("diff:\n%v\nwants\n%v", diff, exp)
 	}
 }
+
+func TestDiffRecords(t *testing.T) {
+	setupSQLs := []string{
+		`CREATE PROCEDURE _migration_exists() BEGIN SELECT 1; END`,
+		`CREATE TABLE pk (id INTEGER NOT NULL, name TEXT, age INTEGER, PRIMARY KEY (id))`,
+		`INSERT INTO pk (id, name, age) VALUES (1, 'alice', 30), (2, 'bob', 24), (3, 'carol', 28)`,
+		`CREATE TABLE nopk (id INTEGER NOT NULL, name TEXT)`,
+		`INSERT INTO nopk (id, name) VALUES (1, 'alice'), (2, 'bob')`,
+	}
+	changeSQLs := []string{
+		`UPDATE pk SET name = 'Alice', age = 31 WHERE id = 1`,
+		`DELETE FROM pk WHERE id = 2`,
+		`INSERT INTO pk (id, name, age) VALUES (4, 'dave', 40)`,
+		`UPDATE pk SET age = 29 WHERE id = 3`,
+		`UPDATE nopk SET name = 'Bob' WHERE id = 2`,
+	}
+	ignores := map[string][]string{"pk": {"age"}}
+
+	db := sqlx.NewDb(testdb.New("db"), "mysql")
+	for _, sql := range setupSQLs {
+		if _, err := db.Exec(sql); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ss, err := TakeSnapshot(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sql := range changeSQLs {
+		if _, err := db.Exec(sql); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := map[string]string{
+		"pk": "" +
+			"pk[id=1].name: 'alice' -> 'Alice'\n" +
+			"pk[id=2] removed: (2, 'bob', 24)\n" +
+			"pk[id=4] added: (4, 'dave', 40)\n",
+		"nopk": "" +
+			"records in \"nopk\" differs:\n" +
+			" (1, 'alice')\n" +
+			"-(2, 'bob')\n" +
+			"+(2, 'Bob')\n",
+	}
+	for table, exp := range tests {
+		t.Run(table, func(t *testing.T) {
+			d, ok, err := diffRecords(db, ss, table, ignores)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !ok {
+				t.Fatalf("no difference found")
+			}
+			if s := d.String(); s != exp {
+				t.Errorf("diff:\n%v\nwants:\n%v", s, exp)
+			}
+		})
+	}
+}
+
+func TestDiffRowsByKeyBytes(t *testing.T) {
+	row := func(vs ...any) Row {
+		r := make(Row, len(vs))
+		for i, v := range vs {
+			r[i] = &v
+		}
+		return r
+	}
+	cols := []string{"id", "name"}
+	before := &Records{Columns: cols, Rows: []Row{row(int64(1), "alice"), row(int64(2), []byte("bob"))}}
+	after := &Records{Columns: cols, Rows: []Row{row(int64(1), []byte("alice")), row(int64(2), []byte("Bob"))}}
+
+	chs := diffRowsByKey("t", before, after, []int{0}, nil)
+	if len(chs) != 1 || chs[0].String() != "t[id=2].name: 'bob' -> 'Bob'" {
+		t.Fatalf("changes: %v", chs)
+	}
+}
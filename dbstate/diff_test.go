@@ -35,16 +35,8 @@ func TestDiff(t *testing.T) {
 	}
 
 	exp := "" +
-		"create table \"users\" differs:\n" +
-		"...\n" +
-		"   `name` varchar(255) NOT NULL,\n" +
-		"+  `age` int,\n" +
-		"   PRIMARY KEY (`id`)\n" +
-		"...\n" +
-		"records in \"user_emails\" differs:\n" +
-		"...\n" +
-		" (2, 'bob2@example.com')\n" +
-		"+(3, 'carol@example.com')\n"
+		"column users.age added: int\n" +
+		"user_emails[user_id=3,email='carol@example.com'] added: (3, 'carol@example.com')\n"
 
 	diff, err = dbstate.Diff(db, ss, nil)
 	if err != nil {
@@ -21,22 +21,35 @@ func (r Row) String() string {
 	}
 	b := []byte{'('}
 	for _, col := range r {
-		switch v := (*col.(*any)).(type) {
-		case int, int8, int16, int32, int64,
-			uint, uint8, uint16, uint32, uint64,
-			float32, float64:
-			b = fmt.Appendf(b, "%v, ", v)
-		case time.Time:
-			b = v.AppendFormat(b, "'2006-01-02 15:04:05', ")
-		default:
-			b = append(b, []byte(quotedValue(v))...)
-			b = append(b, ',', ' ')
-		}
+		b = appendValue(b, *col.(*any))
+		b = append(b, ',', ' ')
 	}
 	b[len(b)-2] = ')'
 	return string(b[:len(b)-1])
 }
 
+func appendValue(b []byte, v any) []byte {
+	switch v := normalize(v).(type) {
+	case int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64,
+		float32, float64:
+		return fmt.Append(b, v)
+	case time.Time:
+		return v.AppendFormat(b, "'2006-01-02 15:04:05'")
+	default:
+		return append(b, []byte(quotedValue(v))...)
+	}
+}
+
+// normalize returns the value to be compared regardless of the driver:
+// go-sql-driver/mysql returns []byte for the text columns while the sandbox returns string.
+func normalize(v any) any {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return v
+}
+
 func quotedValue(v any) string {
 	s := fmt.Sprintf("%v", v)
 	var b strings.Builder
@@ -0,0 +1,415 @@
+package dbstate
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var ErrUnknownDefinition = errors.New("unknown definition")
+
+// TableDef is a table definition parsed from SHOW CREATE TABLE.
+type TableDef struct {
+	Name        string
+	Columns     []Column
+	Indexes     []Index
+	ForeignKeys []Constraint
+	Checks      []Constraint
+	Options     []Option
+}
+
+type Column struct {
+	Name       string
+	Type       string // e.g. "varchar(255)", "int unsigned"
+	Attributes string // e.g. "NOT NULL DEFAULT ''"
+}
+
+type Index struct {
+	Name       string // "PRIMARY" for the primary key
+	Kind       string // "PRIMARY KEY", "UNIQUE KEY", "KEY", ...
+	Definition string // e.g. "(`id`,`name`)"
+}
+
+type Constraint struct {
+	Name       string
+	Definition string // e.g. "FOREIGN KEY (`uid`) REFERENCES `users` (`id`)"
+}
+
+type Option struct {
+	Name  string // e.g. "ENGINE", "DEFAULT CHARSET"
+	Value string
+}
+
+// ParseCreateTable parses the CREATE TABLE statement formatted by SHOW CREATE TABLE.
+func ParseCreateTable(create string) (*TableDef, error) {
+	lines := strings.Split(strings.TrimSpace(create), "\n")
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownDefinition, create)
+	}
+
+	head := strings.TrimSpace(lines[0])
+	if !strings.HasPrefix(head, "CREATE TABLE ") || !strings.HasSuffix(head, "(") {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownDefinition, head)
+	}
+	name, _, ok := identifier(strings.TrimPrefix(head, "CREATE TABLE "))
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownDefinition, head)
+	}
+
+	td := &TableDef{Name: name}
+
+	for _, line := range lines[1:] {
+		line = strings.TrimSuffix(strings.TrimSpace(line), ",")
+		switch {
+		case strings.HasPrefix(line, ")"):
+			td.Options = parseTableOptions(strings.TrimSpace(line[1:]))
+
+		case strings.HasPrefix(line, "`"):
+			name, rest, _ := identifier(line)
+			typ, attrs := splitColumnType(rest)
+			td.Columns = append(td.Columns, Column{Name: name, Type: typ, Attributes: attrs})
+
+		case strings.HasPrefix(line, "PRIMARY KEY "):
+			td.Indexes = append(td.Indexes, Index{
+				Name:       "PRIMARY",
+				Kind:       "PRIMARY KEY",
+				Definition: strings.TrimPrefix(line, "PRIMARY KEY "),
+			})
+
+		case strings.HasPrefix(line, "CONSTRAINT "):
+			name, rest, ok := identifier(strings.TrimPrefix(line, "CONSTRAINT "))
+			if !ok {
+				return nil, fmt.Errorf("%w: %q", ErrUnknownDefinition, line)
+			}
+			c := Constraint{Name: name, Definition: rest}
+			if strings.HasPrefix(rest, "CHECK ") {
+				td.Checks = append(td.Checks, c)
+			} else {
+				td.ForeignKeys = append(td.ForeignKeys, c)
+			}
+
+		default:
+			i := strings.Index(line, "KEY `")
+			if i < 0 {
+				return nil, fmt.Errorf("%w: %q", ErrUnknownDefinition, line)
+			}
+			name, rest, _ := identifier(line[i+len("KEY "):])
+			td.Indexes = append(td.Indexes, Index{
+				Name:       name,
+				Kind:       line[:i+len("KEY")],
+				Definition: rest,
+			})
+		}
+	}
+
+	return td, nil
+}
+
+// PrimaryKey returns the column names of the primary key.
+func (td *TableDef) PrimaryKey() []string {
+	for _, idx := range td.Indexes {
+		if idx.Name == "PRIMARY" {
+			return indexColumns(idx.Definition)
+		}
+	}
+	return nil
+}
+
+// indexColumns returns the column names from the index definition such as "(`a`,`b`(10))".
+func indexColumns(def string) []string {
+	var cols []string
+	s := strings.TrimPrefix(def, "(")
+	for {
+		name, rest, ok := identifier(s)
+		if !ok {
+			return cols
+		}
+		cols = append(cols, name)
+		// skip the prefix length and the order such as "(10) DESC"
+		i := strings.IndexByte(rest, ',')
+		if i < 0 {
+			return cols
+		}
+		s = strings.TrimSpace(rest[i+1:])
+	}
+}
+
+// identifier reads a back-quoted identifier and returns it with the rest of the input.
+func identifier(s string) (ident, rest string, ok bool) {
+	if !strings.HasPrefix(s, "`") {
+		return "", s, false
+	}
+	var sb strings.Builder
+	for i := 1; i < len(s); i++ {
+		if s[i] != '`' {
+			sb.WriteByte(s[i])
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == '`' {
+			sb.WriteByte('`')
+			i++
+			continue
+		}
+		return sb.String(), strings.TrimSpace(s[i+1:]), true
+	}
+	return "", s, false
+}
+
+// splitColumnType splits the column definition into the data type and the other attributes.
+func splitColumnType(def string) (typ, attrs string) {
+	depth := 0
+	var quote byte
+	end := len(def)
+loop:
+	for i := 0; i < len(def); i++ {
+		c := def[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ' ' && depth == 0:
+			end = i
+			break loop
+		}
+	}
+	typ, attrs = def[:end], strings.TrimSpace(def[end:])
+
+	// numeric type modifiers
+	for _, m := range []string{"unsigned", "zerofill"} {
+		if len(attrs) >= len(m) && strings.EqualFold(attrs[:len(m)], m) &&
+			(len(attrs) == len(m) || attrs[len(m)] == ' ') {
+			typ += " " + attrs[:len(m)]
+			attrs = strings.TrimSpace(attrs[len(m):])
+		}
+	}
+	return typ, attrs
+}
+
+// parseTableOptions parses "ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='...'".
+func parseTableOptions(s string) []Option {
+	var opts []Option
+	for s != "" {
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			opts = append(opts, Option{Name: s})
+			break
+		}
+		name := strings.TrimSpace(s[:eq])
+		s = s[eq+1:]
+
+		end := strings.IndexByte(s, ' ')
+		if s != "" && s[0] == '\'' {
+			end = -1
+			for i := 1; i < len(s); i++ {
+				if s[i] == '\\' {
+					i++
+				} else if s[i] == '\'' {
+					if i+1 < len(s) && s[i+1] == '\'' {
+						i++
+						continue
+					}
+					end = i + 1
+					break
+				}
+			}
+		}
+		if end < 0 {
+			end = len(s)
+		}
+		opts = append(opts, Option{Name: name, Value: s[:end]})
+		s = strings.TrimSpace(s[end:])
+	}
+	return opts
+}
+
+type Action int
+
+const (
+	Added Action = iota
+	Removed
+	Modified
+	Renamed
+	Reordered
+)
+
+// SchemaChange is a semantic difference of a table definition.
+type SchemaChange struct {
+	Table  string
+	Object string // "column", "index", "foreign key", "check" or "option"
+	Name   string
+	Action Action
+	Attr   string // modified column attribute: "type" or "attributes"
+	Before string
+	After  string // new name if Renamed
+}
+
+func (c SchemaChange) String() string {
+	switch c.Action {
+	case Added:
+		return fmt.Sprintf("%s %s.%s added: %s", c.Object, c.Table, c.Name, c.After)
+	case Removed:
+		return fmt.Sprintf("%s %s.%s removed: %s", c.Object, c.Table, c.Name, c.Before)
+	case Renamed:
+		return fmt.Sprintf("%s %s.%s renamed to %s", c.Object, c.Table, c.Name, c.After)
+	case Reordered:
+		return fmt.Sprintf("%ss %s reordered: %s -> %s", c.Object, c.Table, c.Before, c.After)
+	}
+	if c.Attr == "attributes" {
+		return fmt.Sprintf("%s %s.%s %s %q -> %q", c.Object, c.Table, c.Name, c.Attr, c.Before, c.After)
+	}
+	if c.Attr != "" {
+		return fmt.Sprintf("%s %s.%s %s %s -> %s", c.Object, c.Table, c.Name, c.Attr, c.Before, c.After)
+	}
+	return fmt.Sprintf("%s %s.%s %s -> %s", c.Object, c.Table, c.Name, c.Before, c.After)
+}
+
+// DiffTableDefs returns the semantic changes from the before to the after table definition.
+// AUTO_INCREMENT counter is not a part of the schema and is ignored.
+func DiffTableDefs(before, after *TableDef) []SchemaChange {
+	var chs []SchemaChange
+	tbl := after.Name
+	add := func(c SchemaChange) {
+		c.Table = tbl
+		chs = append(chs, c)
+	}
+
+	// columns
+	bcols := make(map[string]*Column, len(before.Columns))
+	for i := range before.Columns {
+		bcols[before.Columns[i].Name] = &before.Columns[i]
+	}
+	acols := make(map[string]*Column, len(after.Columns))
+	for i := range after.Columns {
+		acols[after.Columns[i].Name] = &after.Columns[i]
+	}
+	var border, aorder []string
+	for _, bc := range before.Columns {
+		ac, ok := acols[bc.Name]
+		if !ok {
+			add(SchemaChange{Object: "column", Name: bc.Name, Action: Removed, Before: columnDef(bc)})
+			continue
+		}
+		border = append(border, bc.Name)
+		if bc.Type != ac.Type {
+			add(SchemaChange{Object: "column", Name: bc.Name, Action: Modified, Attr: "type", Before: bc.Type, After: ac.Type})
+		}
+		if bc.Attributes != ac.Attributes {
+			add(SchemaChange{Object: "column", Name: bc.Name, Action: Modified, Attr: "attributes", Before: bc.Attributes, After: ac.Attributes})
+		}
+	}
+	for _, ac := range after.Columns {
+		if _, ok := bcols[ac.Name]; !ok {
+			add(SchemaChange{Object: "column", Name: ac.Name, Action: Added, After: columnDef(ac)})
+			continue
+		}
+		aorder = append(aorder, ac.Name)
+	}
+	if !slices.Equal(border, aorder) {
+		add(SchemaChange{Object: "column", Action: Reordered,
+			Before: strings.Join(border, ", "), After: strings.Join(aorder, ", ")})
+	}
+
+	// indexes and constraints
+	for _, c := range diffNamedDefs("index", before.Indexes, after.Indexes,
+		func(i Index) (string, string) { return i.Name, i.Kind + " " + i.Definition }) {
+		add(c)
+	}
+	for _, c := range diffNamedDefs("foreign key", before.ForeignKeys, after.ForeignKeys,
+		func(c Constraint) (string, string) { return c.Name, c.Definition }) {
+		add(c)
+	}
+	for _, c := range diffNamedDefs("check", before.Checks, after.Checks,
+		func(c Constraint) (string, string) { return c.Name, c.Definition }) {
+		add(c)
+	}
+
+	// table options
+	bopts := make(map[string]string, len(before.Options))
+	for _, o := range before.Options {
+		bopts[o.Name] = o.Value
+	}
+	aopts := make(map[string]string, len(after.Options))
+	for _, o := range after.Options {
+		aopts[o.Name] = o.Value
+	}
+	for _, o := range before.Options {
+		if o.Name == "AUTO_INCREMENT" {
+			continue
+		}
+		av, ok := aopts[o.Name]
+		if !ok {
+			add(SchemaChange{Object: "option", Name: o.Name, Action: Removed, Before: o.Value})
+		} else if av != o.Value {
+			add(SchemaChange{Object: "option", Name: o.Name, Action: Modified, Before: o.Value, After: av})
+		}
+	}
+	for _, o := range after.Options {
+		if o.Name == "AUTO_INCREMENT" {
+			continue
+		}
+		if _, ok := bopts[o.Name]; !ok {
+			add(SchemaChange{Object: "option", Name: o.Name, Action: Added, After: o.Value})
+		}
+	}
+
+	return chs
+}
+
+func columnDef(c Column) string {
+	if c.Attributes == "" {
+		return c.Type
+	}
+	return c.Type + " " + c.Attributes
+}
+
+// diffNamedDefs compares the definitions by name.
+// A removed and an added definition with the same content are reported as renamed.
+func diffNamedDefs[T any](object string, before, after []T, nameDef func(T) (string, string)) []SchemaChange {
+	bdefs := make(map[string]string, len(before))
+	for _, b := range before {
+		n, d := nameDef(b)
+		bdefs[n] = d
+	}
+	adefs := make(map[string]string, len(after))
+	for _, a := range after {
+		n, d := nameDef(a)
+		adefs[n] = d
+	}
+
+	var chs, added []SchemaChange
+	for _, a := range after {
+		n, d := nameDef(a)
+		bd, ok := bdefs[n]
+		if !ok {
+			added = append(added, SchemaChange{Object: object, Name: n, Action: Added, After: d})
+		} else if bd != d {
+			chs = append(chs, SchemaChange{Object: object, Name: n, Action: Modified, Before: bd, After: d})
+		}
+	}
+
+	for _, b := range before {
+		n, d := nameDef(b)
+		if _, ok := adefs[n]; ok {
+			continue
+		}
+		i := slices.IndexFunc(added, func(c SchemaChange) bool { return c.After == d })
+		if i < 0 {
+			chs = append(chs, SchemaChange{Object: object, Name: n, Action: Removed, Before: d})
+			continue
+		}
+		chs = append(chs, SchemaChange{Object: object, Name: n, Action: Renamed, Before: d, After: added[i].Name})
+		added = slices.Delete(added, i, i+1)
+	}
+
+	return append(chs, added...)
+}
@@ -0,0 +1,115 @@
+package dbstate_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/makiuchi-d/migy/dbstate"
+)
+
+func TestParseCreateTable(t *testing.T) {
+	create := "CREATE TABLE `users` (\n" +
+		"  `id` int NOT NULL AUTO_INCREMENT,\n" +
+		"  `email` varchar(100) NOT NULL DEFAULT '' COMMENT 'a, b',\n" +
+		"  `d` decimal(10,2),\n" +
+		"  `e` enum('a','b c'),\n" +
+		"  `u` int unsigned,\n" +
+		"  `g``q` int,\n" +
+		"  PRIMARY KEY (`id`),\n" +
+		"  KEY `idx_d` (`d`,`u`),\n" +
+		"  UNIQUE KEY `uq_email` (`email`),\n" +
+		"  CONSTRAINT `fk_user` FOREIGN KEY (`u`) REFERENCES `users` (`id`) ON DELETE CASCADE,\n" +
+		"  CONSTRAINT `users_chk_1` CHECK ((`u` > 0))\n" +
+		") ENGINE=InnoDB AUTO_INCREMENT=2 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_bin COMMENT='users '' tbl'"
+
+	exp := &dbstate.TableDef{
+		Name: "users",
+		Columns: []dbstate.Column{
+			{"id", "int", "NOT NULL AUTO_INCREMENT"},
+			{"email", "varchar(100)", "NOT NULL DEFAULT '' COMMENT 'a, b'"},
+			{"d", "decimal(10,2)", ""},
+			{"e", "enum('a','b c')", ""},
+			{"u", "int unsigned", ""},
+			{"g`q", "int", ""},
+		},
+		Indexes: []dbstate.Index{
+			{"PRIMARY", "PRIMARY KEY", "(`id`)"},
+			{"idx_d", "KEY", "(`d`,`u`)"},
+			{"uq_email", "UNIQUE KEY", "(`email`)"},
+		},
+		ForeignKeys: []dbstate.Constraint{
+			{"fk_user", "FOREIGN KEY (`u`) REFERENCES `users` (`id`) ON DELETE CASCADE"},
+		},
+		Checks: []dbstate.Constraint{
+			{"users_chk_1", "CHECK ((`u` > 0))"},
+		},
+		Options: []dbstate.Option{
+			{"ENGINE", "InnoDB"},
+			{"AUTO_INCREMENT", "2"},
+			{"DEFAULT CHARSET", "utf8mb4"},
+			{"COLLATE", "utf8mb4_0900_bin"},
+			{"COMMENT", "'users '' tbl'"},
+		},
+	}
+
+	td, err := dbstate.ParseCreateTable(create)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(exp, td); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestDiffTableDefs(t *testing.T) {
+	before := "CREATE TABLE `users` (\n" +
+		"  `id` int NOT NULL,\n" +
+		"  `name` varchar(100) NOT NULL,\n" +
+		"  `email` varchar(100) NOT NULL,\n" +
+		"  `age` int,\n" +
+		"  PRIMARY KEY (`id`),\n" +
+		"  KEY `idx_old` (`name`),\n" +
+		"  KEY `idx_age` (`age`)\n" +
+		") ENGINE=InnoDB AUTO_INCREMENT=2 DEFAULT CHARSET=utf8mb4"
+	after := "CREATE TABLE `users` (\n" +
+		"  `id` int NOT NULL,\n" +
+		"  `email` varchar(255) NOT NULL,\n" +
+		"  `name` varchar(100),\n" +
+		"  `group_id` int,\n" +
+		"  PRIMARY KEY (`id`),\n" +
+		"  KEY `idx_name` (`name`),\n" +
+		"  UNIQUE KEY `uq_email` (`email`),\n" +
+		"  CONSTRAINT `fk_group` FOREIGN KEY (`group_id`) REFERENCES `groups` (`id`)\n" +
+		") ENGINE=InnoDB AUTO_INCREMENT=10 DEFAULT CHARSET=utf8mb4 COMMENT='users'"
+
+	exp := []string{
+		`column users.name attributes "NOT NULL" -> ""`,
+		`column users.email type varchar(100) -> varchar(255)`,
+		`column users.age removed: int`,
+		`column users.group_id added: int`,
+		`columns users reordered: id, name, email -> id, email, name`,
+		`index users.idx_old renamed to idx_name`,
+		`index users.idx_age removed: KEY (` + "`age`" + `)`,
+		`index users.uq_email added: UNIQUE KEY (` + "`email`" + `)`,
+		`foreign key users.fk_group added: FOREIGN KEY (` + "`group_id`" + `) REFERENCES ` + "`groups` (`id`)",
+		`option users.COMMENT added: 'users'`,
+	}
+
+	bdef, err := dbstate.ParseCreateTable(before)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adef, err := dbstate.ParseCreateTable(after)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var chs []string
+	for _, c := range dbstate.DiffTableDefs(bdef, adef) {
+		chs = append(chs, c.String())
+	}
+	if diff := cmp.Diff(exp, chs); diff != "" {
+		t.Fatal(diff)
+	}
+}
@@ -0,0 +1,43 @@
+// Package testutil provides the in-memory database for the tests.
+package testutil
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/dolthub/go-mysql-server/driver"
+	"github.com/dolthub/go-mysql-server/memory"
+	sqle "github.com/dolthub/go-mysql-server/sql"
+)
+
+// NewDB returns an in-memory database like testdb.New.
+// Unlike testdb.New which selects the database only on the first connection,
+// every connection selects it, so that the migration lock can hold a dedicated connection.
+func NewDB(name string) *sql.DB {
+	memdb := memory.NewDatabase(name)
+	memdb.EnablePrimaryKeyIndexes()
+	p := &provider{name: name, pro: memory.NewDBProvider(memdb)}
+
+	conn, err := driver.New(p, nil).OpenConnector(name)
+	if err != nil {
+		panic(err)
+	}
+	return sql.OpenDB(conn)
+}
+
+type provider struct {
+	name string
+	pro  *memory.DbProvider
+}
+
+var _ driver.ProviderWithSessionBuilder = (*provider)(nil)
+
+func (p *provider) Resolve(name string, options *driver.Options) (string, sqle.DatabaseProvider, error) {
+	return name, p.pro, nil
+}
+
+func (p *provider) NewSession(ctx context.Context, id uint32, conn *driver.Connector) (sqle.Session, error) {
+	s := memory.NewSession(sqle.NewBaseSession(), p.pro)
+	s.SetCurrentDatabase(p.name)
+	return s, nil
+}
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"bufio"
+	"database/sql"
 	"errors"
 	"fmt"
 	"os"
 	"os/user"
 	"strconv"
+	"strings"
 
 	"github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
@@ -13,6 +16,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 
+	"github.com/makiuchi-d/migy/migrate"
 	"github.com/makiuchi-d/migy/sqlfile"
 )
 
@@ -23,26 +27,44 @@ var cmd = &cobra.Command{
 	SilenceErrors: true,
 	SilenceUsage:  true,
 	Version:       getVersion(),
+
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if _, ok := cmd.Annotations[noConfig]; ok {
+			return nil
+		}
+		return loadConfigFlags(cmd, args, configPath, envName)
+	},
 }
 
+// noConfig is the annotation of the commands which take no settings from the configuration file.
+const noConfig = "migy:no-config"
+
 const signature = "-- Generated by migy (https://github.com/makiuchi-d/migy)"
 
 var (
-	quit      bool
-	targetDir string
-	targetNum int
-	overwrite bool
-	dbHost    string
-	dbPort    int
-	dbUser    string
-	dbPass    string
-	dbDsn     string
+	quit       bool
+	targetDir  string
+	targetNum  int
+	overwrite  bool
+	dbHost     string
+	dbPort     int
+	dbUser     string
+	dbPass     string
+	dbDsn      string
+	cacheDir   string
+	noCache    bool
+	configPath string
+	envName    string
 )
 
 func init() {
 	cmd.PersistentFlags().BoolP("help", "", false, "help for this command") // disable shorthand
 	cmd.PersistentFlags().StringVarP(&targetDir, "dir", "d", ".", "directory with migration files")
 	cmd.PersistentFlags().BoolVarP(&quit, "quit", "q", false, "quit stdout")
+	cmd.PersistentFlags().StringVar(&cacheDir, "cache-dir", "", "directory to cache sandbox states, relative to --dir (default: migy in the user cache directory)")
+	cmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "do not use the sandbox state cache")
+	cmd.PersistentFlags().StringVar(&configPath, "config", "", "configuration file (default: migy.yaml or migy.toml in the working directory)")
+	cmd.PersistentFlags().StringVar(&envName, "env", "", "environment in the configuration file")
 }
 
 // numValue parses integer flags as 10-based number (000010 => 10)
@@ -97,6 +119,43 @@ func info(a ...any) {
 	fmt.Fprintln(os.Stdout, a...)
 }
 
+// askConfirm shows the msg and asks whether to continue, unless yes is given.
+func askConfirm(yes bool, msg func()) bool {
+	if yes {
+		return true
+	}
+	msg()
+	fmt.Print("Do you want to continue? [y/N]: ")
+	s := bufio.NewScanner(os.Stdin)
+	s.Scan()
+	return isYes(s.Text())
+}
+
+// isYes reports whether the answer to the prompt is "y" or "yes".
+func isYes(answer string) bool {
+	in := strings.ToLower(strings.TrimSpace(answer))
+	return in == "y" || in == "yes"
+}
+
+// newMigrator returns a Migrator which outputs to stdout/stderr.
+// The db can be nil.
+func newMigrator(db *sqlx.DB, dir string) *migrate.Migrator {
+	var sdb *sql.DB
+	if db != nil {
+		sdb = db.DB
+	}
+	m := migrate.New(sdb, dir)
+	m.Log = info
+	m.Warn = warning
+	if !noCache {
+		m.CacheDir = cacheDir
+		if m.CacheDir == "" {
+			m.CacheDir = migrate.DefaultCacheDir()
+		}
+	}
+	return m
+}
+
 func openDB(args []string) (*sqlx.DB, error) {
 	if dbHost != "" {
 		return openDBHost(dbUser, dbPass, dbHost, dbPort, args)
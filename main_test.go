@@ -0,0 +1,23 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// keep testdata clean
+	noCache = true
+	os.Exit(m.Run())
+}
+
+func TestIsYes(t *testing.T) {
+	for in, exp := range map[string]bool{
+		"y": true, "Y": true, "yes": true, " Yes\n": true,
+		"": false, "n": false, "no": false, "yess": false,
+	} {
+		if got := isYes(in); got != exp {
+			t.Errorf("isYes(%q) = %v, wants %v", in, got, exp)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/makiuchi-d/migy/dbstate"
+	"github.com/makiuchi-d/migy/migrations"
+	"github.com/makiuchi-d/migy/sqlfile"
+)
+
+var ErrAlreadyInitialized = errors.New("'_migrations' table already exists")
+
+// Baseline creates '_migrations' table and '_migration_exists' procedure on the database
+// which has been managed without migy, and records the migrations up to the target number
+// as applied without executing them.
+// A negative target means the latest migration.
+// The history is recorded in a transaction, and the table and the procedure are dropped again
+// if it fails, so that Baseline can run again.
+// It returns the recorded migrations.
+func (m *Migrator) Baseline(ctx context.Context, target int) (recorded []*migrations.Migration, err error) {
+	if m.db == nil {
+		return nil, ErrNoDatabase
+	}
+	if !m.NoLock {
+		lock, e := acquireLock(ctx, m.db, m.LockTimeout)
+		if e != nil {
+			return nil, e
+		}
+		defer func() {
+			if e := lock.Release(); e != nil && err == nil {
+				err = e
+			}
+		}()
+	}
+
+	if err := dbstate.HasMigrationTable(m.db); !errors.Is(err, dbstate.ErrNoMigrationTable) {
+		if err == nil {
+			err = ErrAlreadyInitialized
+		}
+		return nil, err
+	}
+
+	migs, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	if target < 0 {
+		target = migs.Last().Number
+	}
+	i, err := migs.FindNumber(target)
+	if err != nil {
+		return nil, err
+	}
+
+	m.log("creating: _migrations")
+	if err := sqlfile.ApplyReader(m.db, strings.NewReader(migrations.TableSQL)); err != nil {
+		return nil, err
+	}
+	if err := sqlfile.ApplyReader(m.db, strings.NewReader(migrations.ProcedureSQL)); err != nil {
+		return nil, m.undoBaseline(err, false)
+	}
+	if err := m.recordHistory(ctx, migs[:i+1]); err != nil {
+		return nil, m.undoBaseline(err, true)
+	}
+	return migs[:i+1], nil
+}
+
+// recordHistory inserts the rows of the migrations into '_migrations' table in a transaction.
+func (m *Migrator) recordHistory(ctx context.Context, migs migrations.Migrations) error {
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	const q = "INSERT INTO _migrations (id, applied, title, up_checksum, down_checksum) VALUES (?, now(), ?, ?, ?)"
+	for _, mig := range migs {
+		m.log("recording:", fmt.Sprintf("%06d_%s", mig.Number, mig.Title))
+		_, err := tx.ExecContext(ctx, q, mig.Number, mig.Title, nullString(mig.UpSum), nullString(mig.DownSum))
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// undoBaseline drops '_migrations' table, and '_migration_exists' procedure if created,
+// after Baseline failed with err, so that Baseline can run again.
+func (m *Migrator) undoBaseline(err error, procedure bool) error {
+	_, e := m.db.Exec("DROP TABLE IF EXISTS _migrations")
+	if e == nil && procedure {
+		_, e = m.db.Exec("DROP PROCEDURE IF EXISTS _migration_exists")
+	}
+	if e != nil {
+		return fmt.Errorf("%w; drop '_migrations' table and '_migration_exists' procedure to run baseline again (%v)", err, e)
+	}
+	return err
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
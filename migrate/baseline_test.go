@@ -0,0 +1,117 @@
+package migrate_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/makiuchi-d/migy/dbstate"
+	"github.com/makiuchi-d/migy/internal/testutil"
+	"github.com/makiuchi-d/migy/migrate"
+	"github.com/makiuchi-d/migy/migrations"
+)
+
+func TestBaseline(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewDB("db")
+	defer db.Close()
+
+	// legacy database managed without migy
+	for _, q := range []string{
+		"CREATE TABLE `users` (`id` int NOT NULL PRIMARY KEY AUTO_INCREMENT, `name` varchar(255))",
+		"ALTER TABLE `users` ADD COLUMN `email` VARCHAR(255)",
+		"INSERT INTO `users` (`id`, `name`, `email`) VALUES (1, 'alice', 'alice@example.com')",
+	} {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := migrate.New(db, filepath.Join("testdata", "snapshot"))
+
+	diff, err := m.Verify(ctx, 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff == "" {
+		t.Fatalf("diff at 10 must not be empty")
+	}
+	diff, err = m.Verify(ctx, 20, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff != "" {
+		t.Fatalf("diff at 20:\n%v", diff)
+	}
+
+	recorded, err := m.Baseline(ctx, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recorded) != 3 || recorded[2].Number != 20 {
+		t.Fatalf("recorded: %+v", recorded)
+	}
+
+	hists, err := migrations.LoadHistories(sqlx.NewDb(db, "mysql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := hists.CurrentNum(); n != 20 {
+		t.Fatalf("current: %v", n)
+	}
+
+	plan, err := m.Plan(ctx, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Steps) != 0 || len(plan.Modified) != 0 {
+		t.Fatalf("plan: steps=%v modified=%v", plan.Files(), plan.Modified)
+	}
+
+	// down migrations work with '_migration_exists' procedure
+	if _, err := m.Down(ctx, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = m.Baseline(ctx, 20)
+	if !errors.Is(err, migrate.ErrAlreadyInitialized) {
+		t.Fatalf("baseline twice must be ErrAlreadyInitialized: %v", err)
+	}
+}
+
+func TestBaselineFailure(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewDB("db")
+	defer db.Close()
+
+	// the title is too long for '_migrations' table
+	long := strings.Repeat("x", 300)
+	fsys := fstest.MapFS{
+		"000010_first.up.sql":          &fstest.MapFile{},
+		"000010_first.down.sql":        &fstest.MapFile{},
+		"000020_" + long + ".up.sql":   &fstest.MapFile{},
+		"000020_" + long + ".down.sql": &fstest.MapFile{},
+	}
+	m := migrate.NewFS(db, fsys)
+	if _, err := m.Baseline(ctx, -1); err == nil || !strings.Contains(err.Error(), "too large for column 'title'") {
+		t.Fatalf("baseline must fail at recording 000020: %v", err)
+	}
+
+	// nothing is left, so that baseline can run again
+	sdb := sqlx.NewDb(db, "mysql")
+	if err := dbstate.HasMigrationTable(sdb); !errors.Is(err, dbstate.ErrNoMigrationTable) {
+		t.Fatalf("_migrations table must be dropped: %v", err)
+	}
+	recorded, err := migrate.NewFS(db, fstest.MapFS{"000010_first.up.sql": fsys["000010_first.up.sql"], "000010_first.down.sql": fsys["000010_first.down.sql"]}).Baseline(ctx, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recorded) != 1 {
+		t.Fatalf("recorded: %+v", recorded)
+	}
+}
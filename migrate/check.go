@@ -0,0 +1,364 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/makiuchi-d/migy/dbstate"
+	"github.com/makiuchi-d/migy/migrations"
+	"github.com/makiuchi-d/migy/sqlfile"
+)
+
+var ErrNoCheck = errors.New("no migration to check")
+
+// CheckResult is the result of the check of a migration.
+type CheckResult struct {
+	Number int
+	Diff   string // differences found; empty when the check passed
+}
+
+// Check checks the up/down migration of the number in a temporary database.
+// A negative number means the latest migration.
+func (m *Migrator) Check(ctx context.Context, num int) (*CheckResult, error) {
+	migs, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+
+	if num >= 0 {
+		i, err := migs.FindNumber(num)
+		if err != nil {
+			return nil, err
+		}
+		migs = migs[:i+1]
+	}
+
+	if len(migs) < 2 {
+		return nil, ErrNoCheck
+	}
+
+	mig := migs.Last()
+	if !mig.UpDown {
+		return nil, fmt.Errorf("no up/down migration: number=%06d", mig.Number)
+	}
+
+	files, err := migs[:len(migs)-1].FileNamesFromSnapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := m.openSandbox(files, m.log)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	diff, err := checkStep(db, m.fsys, m.withIgnores(mig), m.RoundTrip, m.log)
+	if err != nil {
+		return nil, err
+	}
+	return &CheckResult{Number: mig.Number, Diff: diff}, nil
+}
+
+// CheckRange checks the migrations from a number to another step by step.
+// A negative to means the latest migration.
+// The migrations are split into contiguous ranges for each of Jobs,
+// and each job reuses the state after a step as the base of the next step.
+// It returns the results of the checked migrations in order and stops at the first failure.
+func (m *Migrator) CheckRange(ctx context.Context, from, to int) ([]CheckResult, error) {
+	if to >= 0 && from > to {
+		return nil, fmt.Errorf("from (%06d) must be less than %06d", from, to)
+	}
+	migs, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	f, err := migs.FindNumber(from)
+	if err != nil {
+		return nil, err
+	}
+	t := len(migs) - 1
+	if to >= 0 {
+		t, err = migs.FindNumber(to)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	n := t + 1 - f
+	jobs := max(min(m.Jobs, n), 1)
+	if jobs == 1 {
+		return m.checkRange(ctx, migs, f, t+1, m.log, nil)
+	}
+
+	type job struct {
+		logs    [][]any
+		results []CheckResult
+		err     error
+		done    chan struct{}
+	}
+	// the jobs after the first failed one stop
+	var first atomic.Int64
+	first.Store(int64(jobs))
+	js := make([]job, jobs)
+	for i := range js {
+		j := &js[i]
+		j.done = make(chan struct{})
+		s, e := f+i*n/jobs, f+(i+1)*n/jobs
+		go func() {
+			defer close(j.done)
+			log := func(a ...any) { j.logs = append(j.logs, a) }
+			stop := func() bool { return first.Load() < int64(i) }
+			j.results, j.err = m.checkRange(ctx, migs, s, e, log, stop)
+			if j.err != nil || failed(j.results) {
+				for {
+					v := first.Load()
+					if v <= int64(i) || first.CompareAndSwap(v, int64(i)) {
+						break
+					}
+				}
+			}
+		}()
+	}
+
+	// output the logs and results in order of the migrations
+	var results []CheckResult
+	for i := range js {
+		j := &js[i]
+		<-j.done
+		for _, a := range j.logs {
+			m.log(a...)
+		}
+		results = append(results, j.results...)
+		if j.err != nil || failed(j.results) {
+			return results, j.err
+		}
+	}
+	return results, nil
+}
+
+func failed(results []CheckResult) bool {
+	return len(results) > 0 && results[len(results)-1].Diff != ""
+}
+
+// checkRange checks migs[s:e] on a single database.
+// It stops before the next step when stop returns true.
+func (m *Migrator) checkRange(ctx context.Context, migs migrations.Migrations, s, e int, log func(...any), stop func() bool) ([]CheckResult, error) {
+	if s == 0 {
+		return nil, ErrNoCheck
+	}
+	files, err := migs[:s].FileNamesFromSnapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := m.openSandbox(files, log)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var results []CheckResult
+	for _, mig := range migs[s:e] {
+		if stop != nil && stop() {
+			return results, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		log(fmt.Sprintf("==== check %06d", mig.Number))
+		if !mig.UpDown {
+			return results, fmt.Errorf("no up/down migration: number=%06d", mig.Number)
+		}
+		diff, err := checkStep(db, m.fsys, m.withIgnores(mig), m.RoundTrip, log)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, CheckResult{Number: mig.Number, Diff: diff})
+		if diff != "" {
+			return results, nil
+		}
+		if m.RoundTrip {
+			continue
+		}
+
+		// advance the state to the base of the next step
+		log("applying:", mig.UpName())
+		if err := mig.ApplyFS(db, m.fsys, mig.UpName()); err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// withIgnores returns a copy of the migration with Ignores added to its ignores.
+func (m *Migrator) withIgnores(mig *migrations.Migration) *migrations.Migration {
+	if len(m.Ignores) == 0 {
+		return mig
+	}
+	c := *mig
+	c.Ignores = mergeIgnores(mig.Ignores, m.Ignores)
+	return &c
+}
+
+// mergeIgnores returns the columns to ignore in both a and b.
+func mergeIgnores(a, b map[string][]string) map[string][]string {
+	ign := make(map[string][]string, len(a)+len(b))
+	for t, cols := range a {
+		ign[t] = slices.Clone(cols)
+	}
+	for t, cols := range b {
+		ign[t] = append(ign[t], cols...)
+	}
+	return ign
+}
+
+// checkFixture checks the migration on a copy of the db with the fixture rows loaded.
+// The snapshot is not compared since it does not contain the fixture rows.
+func checkFixture(db *sqlx.DB, fsys fs.FS, mig *migrations.Migration, roundTrip bool, log func(...any)) (string, error) {
+	log("---- fixture")
+	fdb, err := cloneSandbox(db)
+	if err != nil {
+		return "", err
+	}
+	defer fdb.Close()
+
+	log("applying:", mig.FixtureName())
+	if err := sqlfile.ApplyFS(fdb, fsys, mig.FixtureName()); err != nil {
+		return "", err
+	}
+
+	m := *mig
+	m.Fixture = false
+	m.Snapshot = false
+	diff, err := checkStep(fdb, fsys, &m, roundTrip, log)
+	if err != nil || diff == "" {
+		return "", err
+	}
+	return fmt.Sprintf("with %s:\n%s", mig.FixtureName(), diff), nil
+}
+
+// checkStep checks the up/down migration on the db in the state before the migration.
+// The db is left in the same state when the check succeeds,
+// or in the state after the up migration with roundTrip.
+func checkStep(db *sqlx.DB, fsys fs.FS, mig *migrations.Migration, roundTrip bool, log func(...any)) (string, error) {
+	if mig.Fixture {
+		diff, err := checkFixture(db, fsys, mig, roundTrip, log)
+		if err != nil || diff != "" {
+			return diff, err
+		}
+	}
+
+	// snapshot for up/down check
+	ss, err := dbstate.TakeSnapshot(db)
+	if err != nil {
+		return "", err
+	}
+
+	log("---- up/down")
+	log("applying:", mig.UpName())
+	if err := mig.ApplyFS(db, fsys, mig.UpName()); err != nil {
+		return "", err
+	}
+	if diff, err := checkAsserts(db, mig.UpName(), mig.UpAsserts); err != nil || diff != "" {
+		return diff, err
+	}
+
+	// snapshot for .all.sql and round-trip check
+	var ss2 *dbstate.Snapshot
+	if mig.Snapshot || roundTrip {
+		ss2, err = dbstate.TakeSnapshot(db)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	log("applying:", mig.DownName())
+	if err := mig.ApplyFS(db, fsys, mig.DownName()); err != nil {
+		return "", err
+	}
+	if diff, err := checkAsserts(db, mig.DownName(), mig.DownAsserts); err != nil || diff != "" {
+		return diff, err
+	}
+
+	log("checking...")
+	diff, err := dbstate.Diff(db, ss, mig.Ignores)
+	if err != nil {
+		return "", err
+	}
+	diff = strings.TrimSuffix(diff, "\n")
+	if diff != "" && !roundTrip {
+		return diff, nil
+	}
+	if diff == "" {
+		log("ok")
+	}
+
+	if roundTrip {
+		// the up migration must work again after the down migration
+		log("---- up/down/up")
+		log("applying:", mig.UpName())
+		var rt string
+		if err := mig.ApplyFS(db, fsys, mig.UpName()); err != nil {
+			rt = err.Error()
+		} else {
+			log("checking...")
+			ign := mergeIgnores(map[string][]string{"_migrations": {"applied"}}, mig.Ignores)
+			rt, err = dbstate.Diff(db, ss2, ign)
+			if err != nil {
+				return "", err
+			}
+			rt = strings.TrimSuffix(rt, "\n")
+		}
+		if diff != "" || rt != "" {
+			var sb strings.Builder
+			if diff != "" {
+				fmt.Fprintf(&sb, "---- up/down\n%s\n", diff)
+			}
+			if rt != "" {
+				fmt.Fprintf(&sb, "---- up/down/up\n%s\n", rt)
+			}
+			return strings.TrimSuffix(sb.String(), "\n"), nil
+		}
+		log("ok")
+	}
+
+	if !mig.Snapshot {
+		return "", nil
+	}
+
+	log("---- snapshot")
+	db2 := newSandboxDB("db2")
+	defer db2.Close()
+	log("applying:", mig.SnapshotName())
+	if err := sqlfile.ApplyFS(db2, fsys, mig.SnapshotName()); err != nil {
+		return "", err
+	}
+	log("checking...")
+	diff, err = dbstate.Diff(db2, ss2, map[string][]string{"_migrations": {"applied"}})
+	if err != nil {
+		return "", err
+	}
+	if diff != "" {
+		return strings.TrimSuffix(diff, "\n"), nil
+	}
+	log("ok")
+
+	return "", nil
+}
+
+// checkAsserts returns the failed assertion of the file as a difference.
+func checkAsserts(db *sqlx.DB, file string, asserts []string) (string, error) {
+	err := migrations.Assert(db, asserts)
+	if errors.Is(err, migrations.ErrAssertion) {
+		return fmt.Sprintf("%s: %v", file, err), nil
+	}
+	return "", err
+}
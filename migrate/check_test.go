@@ -0,0 +1,61 @@
+package migrate_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/makiuchi-d/migy/migrate"
+)
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+
+	m := migrate.New(nil, filepath.Join("testdata", "check", "success"))
+	r, err := m.Check(ctx, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Number != 50 || r.Diff != "" {
+		t.Fatalf("check latest: %+v", r)
+	}
+
+	m = migrate.New(nil, filepath.Join("testdata", "check", "schema"))
+	r, err = m.Check(ctx, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "column table1.val2 added: int DEFAULT '0'"; r.Diff != exp {
+		t.Fatalf("check 20: %q wants %q", r.Diff, exp)
+	}
+}
+
+func TestCheckRange(t *testing.T) {
+	ctx := context.Background()
+
+	for _, jobs := range []int{1, 2, 10} {
+		m := migrate.New(nil, filepath.Join("testdata", "check", "success"))
+		m.Jobs = jobs
+		rs, err := m.CheckRange(ctx, 10, 40)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exp := []migrate.CheckResult{{10, ""}, {20, ""}, {30, ""}, {40, ""}}
+		if d := cmp.Diff(exp, rs); d != "" {
+			t.Fatalf("jobs=%v:\n%v", jobs, d)
+		}
+	}
+
+	m := migrate.New(nil, filepath.Join("testdata", "check", "schema"))
+	m.Jobs = 2
+	rs, err := m.CheckRange(ctx, 10, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []migrate.CheckResult{{10, ""}, {20, "column table1.val2 added: int DEFAULT '0'"}}
+	if d := cmp.Diff(exp, rs); d != "" {
+		t.Fatal(d)
+	}
+}
@@ -0,0 +1,103 @@
+package migrate_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/makiuchi-d/migy/dbstate"
+	"github.com/makiuchi-d/migy/internal/testutil"
+	"github.com/makiuchi-d/migy/migrate"
+	"github.com/makiuchi-d/migy/migrations"
+)
+
+func hashNames(db sqlx.Ext) error {
+	var users []struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+	if err := sqlx.Select(db, &users, "SELECT id, name FROM users"); err != nil {
+		return err
+	}
+	for _, u := range users {
+		sum := sha256.Sum256([]byte(u.Name))
+		if _, err := db.Exec("UPDATE users SET hash = ? WHERE id = ?", hex.EncodeToString(sum[:]), u.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestGoMigration(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join("testdata", "gofunc")
+
+	clear := func(db sqlx.Ext) error {
+		_, err := db.Exec("UPDATE users SET hash = ''")
+		return err
+	}
+	gos := []*migrations.Migration{migrations.Go(20, "hash_names", hashNames, clear)}
+
+	c := migrate.New(nil, dir)
+	c.GoMigrations = gos
+	r, err := c.Check(ctx, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Diff != "" {
+		t.Fatalf("check 20:\n%v", r.Diff)
+	}
+
+	db := testutil.NewDB("db")
+	defer db.Close()
+	m := migrate.New(db, dir)
+	m.GoMigrations = gos
+	steps, err := m.Up(ctx, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 3 || steps[2].File != "000020_hash_names.up.go" {
+		t.Fatalf("up: %+v", steps)
+	}
+	recs, err := dbstate.GetRecords(sqlx.NewDb(db, "mysql"), "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := recs.Rows[0].String(); s != "(1, 'user1', '0a041b9462caa4a31bac3567e0b6e6fd9100787db2ab433d96f6d178cabfce90')" {
+		t.Fatalf("hashed: %v", s)
+	}
+
+	sts, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sts[2].IsApplied() || sts[2].Modified {
+		t.Fatalf("status: %+v", sts[2])
+	}
+
+	steps, err = m.Down(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 1 || steps[0].File != "000020_hash_names.down.go" {
+		t.Fatalf("down: %+v", steps)
+	}
+}
+
+func TestGoMigrationIrreversible(t *testing.T) {
+	nop := func(db sqlx.Ext) error { return nil }
+	m := migrate.New(nil, filepath.Join("testdata", "gofunc"))
+	m.GoMigrations = []*migrations.Migration{migrations.Go(20, "hash_names", hashNames, nop)}
+
+	r, err := m.Check(context.Background(), 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Diff == "" {
+		t.Fatalf("irreversible migration must be detected")
+	}
+}
@@ -0,0 +1,110 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrLocked = errors.New("migration locked")
+
+const lockPrefix = "migy."
+
+// migrationLock is a MySQL named lock held on a dedicated connection.
+type migrationLock struct {
+	conn *sqlx.Conn
+	name string
+}
+
+// acquireLock takes the named lock for the current database.
+// It waits up to timeout for the lock (negative timeout waits forever).
+func acquireLock(ctx context.Context, db *sqlx.DB, timeout time.Duration) (*migrationLock, error) {
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var dbname string
+	err = conn.GetContext(ctx, &dbname, "SELECT IFNULL(DATABASE(), '')")
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	name := lockName(dbname)
+
+	sec := timeout.Seconds()
+	if timeout < 0 {
+		sec = -1
+	}
+	var ok sql.NullInt64
+	err = conn.GetContext(ctx, &ok, "SELECT GET_LOCK(?, ?)", name, sec)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !ok.Valid || ok.Int64 != 1 {
+		err := lockHolderError(ctx, conn, name)
+		conn.Close()
+		return nil, err
+	}
+
+	return &migrationLock{conn: conn, name: name}, nil
+}
+
+// maxLockName is the maximum length of the lock name in MySQL.
+const maxLockName = 64
+
+// lockName returns the name of the lock for the database.
+// The name of a long database is hashed to fit in the maximum length.
+func lockName(dbname string) string {
+	name := lockPrefix + dbname
+	if len(name) <= maxLockName {
+		return name
+	}
+	sum := sha256.Sum256([]byte(dbname))
+	return lockPrefix + hex.EncodeToString(sum[:])[:maxLockName-len(lockPrefix)]
+}
+
+// Release releases the lock and closes the dedicated connection.
+func (l *migrationLock) Release() error {
+	defer l.conn.Close()
+	var ok sql.NullInt64
+	err := l.conn.GetContext(context.Background(), &ok, "SELECT RELEASE_LOCK(?)", l.name)
+	if err != nil {
+		return err
+	}
+	if !ok.Valid || ok.Int64 != 1 {
+		return fmt.Errorf("lock %q is not held by this connection", l.name)
+	}
+	return nil
+}
+
+func lockHolderError(ctx context.Context, conn *sqlx.Conn, name string) error {
+	var id sql.NullInt64
+	err := conn.GetContext(ctx, &id, "SELECT IS_USED_LOCK(?)", name)
+	if err != nil || !id.Valid {
+		return fmt.Errorf("%w: %q", ErrLocked, name)
+	}
+
+	// the lock connection stays idle while holding the lock,
+	// so its processlist time tells when the lock was taken.
+	const q = "SELECT USER, HOST, TIME FROM information_schema.PROCESSLIST WHERE ID = ?"
+	var p struct {
+		User string `db:"USER"`
+		Host string `db:"HOST"`
+		Time int64  `db:"TIME"`
+	}
+	err = conn.GetContext(ctx, &p, q, id.Int64)
+	if err != nil {
+		return fmt.Errorf("%w: %q by connection %d", ErrLocked, name, id.Int64)
+	}
+	since := time.Now().Add(-time.Duration(p.Time) * time.Second)
+	return fmt.Errorf("%w: %q by connection %d (%s@%s) since %s",
+		ErrLocked, name, id.Int64, p.User, p.Host, since.Format("2006-01-02 15:04:05"))
+}
@@ -0,0 +1,82 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/makiuchi-d/testdb"
+)
+
+func TestAcquireLock(t *testing.T) {
+	ctx := context.Background()
+	db := sqlx.NewDb(testdb.New("db"), "mysql")
+	defer db.Close()
+
+	lock, err := acquireLock(ctx, db, 0)
+	if err != nil {
+		t.Fatalf("acquireLock: %v", err)
+	}
+	if lock.name != "migy.db" {
+		t.Errorf("lock name = %q, wants %q", lock.name, "migy.db")
+	}
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if err := lock.Release(); err == nil {
+		t.Errorf("Release twice must be error")
+	}
+}
+
+func TestLockName(t *testing.T) {
+	if n := lockName("db"); n != "migy.db" {
+		t.Errorf("lock name = %q, wants %q", n, "migy.db")
+	}
+	name59 := strings.Repeat("a", 59)
+	if n := lockName(name59); n != "migy."+name59 {
+		t.Errorf("lock name = %q, wants %q", n, "migy."+name59)
+	}
+	long := lockName(strings.Repeat("a", 64))
+	if len(long) != 64 || !strings.HasPrefix(long, "migy.") {
+		t.Errorf("lock name of long database = %q", long)
+	}
+	if long == lockName(strings.Repeat("a", 63)+"b") {
+		t.Errorf("lock names of different databases must differ")
+	}
+}
+
+func TestLockHolderError(t *testing.T) {
+	ctx := context.Background()
+	db := sqlx.NewDb(testdb.New("db"), "mysql")
+	defer db.Close()
+
+	holder, err := db.Connx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer holder.Close()
+	var id int64
+	if err := holder.GetContext(ctx, &id, "SELECT CONNECTION_ID()"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := holder.ExecContext(ctx, "SELECT GET_LOCK('migy.test', 0)"); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	err = lockHolderError(ctx, conn, "migy.test")
+	if !errors.Is(err, ErrLocked) {
+		t.Fatalf("error: %v, wants %v", err, ErrLocked)
+	}
+	if !strings.Contains(err.Error(), "by connection "+strconv.FormatInt(id, 10)) {
+		t.Errorf("error must contain the holder connection %d: %v", id, err)
+	}
+}
@@ -0,0 +1,529 @@
+// Package migrate runs the migrations in a directory or an fs.FS against a database.
+// It provides the operations of the migy command for application code.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/makiuchi-d/migy/dbstate"
+	"github.com/makiuchi-d/migy/migrations"
+	"github.com/makiuchi-d/migy/sqlfile"
+)
+
+var (
+	ErrModified       = errors.New("files modified after applied")
+	ErrDirection      = errors.New("wrong direction")
+	ErrNotInitialized = errors.New("'_migrations' table found but not initialized")
+	ErrNoDatabase     = errors.New("no database")
+	ErrOutOfOrder     = errors.New("unapplied migrations below the current number")
+	ErrPlanChanged    = errors.New("the database has changed since the plan")
+)
+
+// Migrator runs the migrations in the directory or the fs.FS.
+// The fields are the options and must be set before calling the methods.
+type Migrator struct {
+	db   *sqlx.DB
+	fsys fs.FS
+	dir  string // empty for fs.FS
+
+	Force        bool                    // apply even if the applied migration files have been modified
+	OutOfOrder   bool                    // apply the unapplied migrations below the current number based on the history
+	Resume       bool                    // continue the partially applied file from the failed statement
+	Assert       bool                    // run the migy:assert annotations after each file on apply
+	NoLock       bool                    // do not take the migration lock on apply
+	LockTimeout  time.Duration           // time to wait for the migration lock (negative waits forever)
+	Tx           bool                    // apply each up/down file in a transaction
+	RoundTrip    bool                    // check up/down/up in addition to up/down
+	Jobs         int                     // number of parallel jobs of CheckRange
+	Ignores      map[string][]string     // columns not compared after the down migration in Check, in addition to the migy:ignore annotations
+	GoMigrations []*migrations.Migration // Go migrations merged into the migration files (see migrations.Go)
+	CacheDir     string                  // directory to cache sandbox states (relative to the migration directory, or the current directory for fs.FS); empty disables the cache; states unused for 30 days are pruned
+
+	Log  func(a ...any)   // progress output
+	Warn func(msg string) // warning output
+}
+
+// New returns a Migrator for the migration files in dir.
+// The db can be nil for the operations without a database (Status, Check).
+func New(db *sql.DB, dir string) *Migrator {
+	m := NewFS(db, os.DirFS(dir))
+	m.dir = dir
+	return m
+}
+
+// NewFS returns a Migrator for the migration files in the root of fsys, such as embed.FS.
+// The db can be nil for the operations without a database (Status, Check).
+func NewFS(db *sql.DB, fsys fs.FS) *Migrator {
+	m := &Migrator{
+		fsys:        fsys,
+		LockTimeout: 10 * time.Second,
+		Jobs:        1,
+	}
+	if db != nil {
+		m.db = sqlx.NewDb(db, "mysql")
+	}
+	return m
+}
+
+// load returns the migration files and the Go migrations.
+func (m *Migrator) load() (migrations.Migrations, error) {
+	return migrations.LoadFS(m.fsys, m.GoMigrations...)
+}
+
+func (m *Migrator) log(a ...any) {
+	if m.Log != nil {
+		m.Log(a...)
+	}
+}
+
+func (m *Migrator) warn(msg string) {
+	if m.Warn != nil {
+		m.Warn(msg)
+	}
+}
+
+// Step is a migration file to apply.
+type Step struct {
+	Migration *migrations.Migration
+	File      string
+	Down      bool
+}
+
+// Plan is the migration files to apply to reach the target.
+type Plan struct {
+	Current  int // -1 when the database has no '_migrations' table
+	Target   int
+	Steps    []Step
+	Modified []*migrations.Migration // applied migrations whose files have been modified
+	Partial  *Progress               // file failed halfway in the last apply
+
+	// OutOfOrder is the unapplied migrations below the current number.
+	// They are included in Steps only with Migrator.OutOfOrder,
+	// and a downgrade rolls back only the applied migrations based on the history.
+	OutOfOrder []*migrations.Migration
+
+	replan func(context.Context) (*Plan, error) // builds the plan again under the lock
+}
+
+// Files returns the file names of the steps.
+func (p *Plan) Files() []string {
+	var files []string
+	for _, s := range p.Steps {
+		files = append(files, s.File)
+	}
+	return files
+}
+
+// same reports whether q has the same steps and partially applied file as p.
+func (p *Plan) same(q *Plan) bool {
+	if p.Current != q.Current || p.Target != q.Target || !slices.Equal(p.Files(), q.Files()) {
+		return false
+	}
+	if p.Partial == nil || q.Partial == nil {
+		return p.Partial == q.Partial
+	}
+	return *p.Partial == *q.Partial
+}
+
+// ModifiedError returns ErrModified with the modified migrations, or nil if nothing modified.
+func (p *Plan) ModifiedError() error {
+	if len(p.Modified) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(p.Modified))
+	for _, m := range p.Modified {
+		names = append(names, fmt.Sprintf("%06d_%s", m.Number, m.Title))
+	}
+	return fmt.Errorf("%w: %s", ErrModified, strings.Join(names, ", "))
+}
+
+// OutOfOrderError returns ErrOutOfOrder with the out-of-order migrations, or nil if nothing.
+func (p *Plan) OutOfOrderError() error {
+	if len(p.OutOfOrder) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(p.OutOfOrder))
+	for _, m := range p.OutOfOrder {
+		names = append(names, fmt.Sprintf("%06d_%s", m.Number, m.Title))
+	}
+	return fmt.Errorf("%w: %s", ErrOutOfOrder, strings.Join(names, ", "))
+}
+
+// PartialError returns ErrPartiallyApplied with the partially applied file, or nil if nothing.
+func (p *Plan) PartialError() error {
+	if p.Partial == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: %s (%d statements executed)", ErrPartiallyApplied, p.Partial.File, p.Partial.Stmt)
+}
+
+// Status returns the status of each migration.
+// The migrations are not applied to any database when the db is nil.
+func (m *Migrator) Status(ctx context.Context) ([]migrations.Status, error) {
+	var hists []migrations.History
+	if m.db != nil {
+		err := dbstate.HasMigrationTable(m.db)
+		if !errors.Is(err, dbstate.ErrNoMigrationTable) {
+			hists, err = migrations.LoadHistories(m.db)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	migs, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+
+	var sts []migrations.Status
+	for st := range migrations.BuildStatus(migs, hists) {
+		sts = append(sts, st)
+	}
+	return sts, nil
+}
+
+// Plan returns the migration files to apply to reach the target number.
+// A negative target means the latest migration.
+func (m *Migrator) Plan(ctx context.Context, target int) (*Plan, error) {
+	if m.db == nil {
+		return nil, ErrNoDatabase
+	}
+	migs, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	if target < 0 {
+		target = migs.Last().Number
+	}
+
+	err = dbstate.HasMigrationTable(m.db)
+	if err != nil {
+		if !errors.Is(err, dbstate.ErrNoMigrationTable) {
+			return nil, err
+		}
+
+		// apply files from snapshot
+		i, err := migs.FindNumber(target)
+		if err != nil {
+			return nil, err
+		}
+		files, err := migs[:i+1].FileNamesFromSnapshot()
+		if err != nil {
+			return nil, err
+		}
+		plan := &Plan{Current: -1, Target: target, Steps: buildSteps(migs, files)}
+		plan.replan = func(ctx context.Context) (*Plan, error) { return m.Plan(ctx, target) }
+		return plan, nil
+	}
+
+	hists, err := migrations.LoadHistories(m.db)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotInitialized
+		}
+		return nil, err
+	}
+	cur := hists.CurrentNum()
+
+	ms := make(migrations.Migrations, 0, len(migs))
+	var mods, ooo []*migrations.Migration
+	for s := range migrations.BuildStatus(migs, hists) {
+		ms = append(ms, s.Migration)
+		if s.Modified {
+			mods = append(mods, s.Migration)
+		}
+		if s.OutOfOrder {
+			ooo = append(ooo, s.Migration)
+		}
+	}
+
+	var files []string
+	switch {
+	case m.OutOfOrder:
+		files, err = ms.FileNamesToApplyOutOfOrder(hists, target)
+	case target < cur && len(ooo) > 0:
+		// the unapplied migrations must not be rolled back
+		files, err = ms.FileNamesToRollback(hists, target)
+	default:
+		files, err = ms.FileNamesToApply(cur, target)
+	}
+	if err != nil {
+		return nil, err
+	}
+	partial, err := loadProgress(m.db)
+	if err != nil {
+		return nil, err
+	}
+	plan := &Plan{Current: cur, Target: target, Steps: buildSteps(ms, files), Modified: mods, Partial: partial, OutOfOrder: ooo}
+	plan.replan = func(ctx context.Context) (*Plan, error) { return m.Plan(ctx, target) }
+	return plan, nil
+}
+
+func buildSteps(migs migrations.Migrations, files []string) []Step {
+	steps := make(map[string]Step, len(migs)*3)
+	for _, m := range migs {
+		steps[m.SnapshotName()] = Step{Migration: m, File: m.SnapshotName()}
+		steps[m.UpName()] = Step{Migration: m, File: m.UpName()}
+		steps[m.DownName()] = Step{Migration: m, File: m.DownName(), Down: true}
+	}
+	ss := make([]Step, len(files))
+	for i, f := range files {
+		ss[i] = steps[f]
+	}
+	return ss
+}
+
+// Up applies the up migrations to reach the target number.
+// A negative target means the latest migration.
+// The unapplied migrations below the current number are skipped unless OutOfOrder (see Plan.OutOfOrder).
+// It returns the applied steps even if an error occurs.
+func (m *Migrator) Up(ctx context.Context, target int) ([]Step, error) {
+	return m.apply(ctx, target, false)
+}
+
+// Down applies the down migrations to reach the target number.
+// With OutOfOrder, the up migrations following the down migrations are also applied.
+// It returns the applied steps even if an error occurs.
+func (m *Migrator) Down(ctx context.Context, target int) ([]Step, error) {
+	return m.apply(ctx, target, true)
+}
+
+func (m *Migrator) apply(ctx context.Context, target int, down bool) (steps []Step, err error) {
+	if m.db == nil {
+		return nil, ErrNoDatabase
+	}
+	if !m.NoLock {
+		lock, e := acquireLock(ctx, m.db, m.LockTimeout)
+		if e != nil {
+			return nil, e
+		}
+		defer func() {
+			if e := lock.Release(); e != nil && err == nil {
+				err = e
+			}
+		}()
+	}
+
+	plan, err := m.Plan(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	if len(plan.Steps) > 0 && plan.Steps[0].Down != down {
+		return nil, fmt.Errorf("%w: current=%06d target=%06d", ErrDirection, plan.Current, plan.Target)
+	}
+	return m.run(ctx, plan)
+}
+
+// Apply applies the plan returned by Plan or RedoPlan, such as the one confirmed by the user.
+// It takes the lock and builds the plan again, then refuses to run with ErrPlanChanged
+// if the steps or the partially applied file differ from the plan.
+// It returns the applied steps even if an error occurs.
+func (m *Migrator) Apply(ctx context.Context, plan *Plan) (steps []Step, err error) {
+	if m.db == nil {
+		return nil, ErrNoDatabase
+	}
+	if !m.NoLock {
+		lock, e := acquireLock(ctx, m.db, m.LockTimeout)
+		if e != nil {
+			return nil, e
+		}
+		defer func() {
+			if e := lock.Release(); e != nil && err == nil {
+				err = e
+			}
+		}()
+	}
+
+	if plan.replan == nil {
+		return nil, errors.New("the plan is not built by Plan or RedoPlan")
+	}
+	cur, err := plan.replan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !plan.same(cur) {
+		return nil, fmt.Errorf("%w: %v -> %v", ErrPlanChanged, plan.Files(), cur.Files())
+	}
+	return m.run(ctx, cur)
+}
+
+// run applies the plan under the lock.
+func (m *Migrator) run(ctx context.Context, plan *Plan) (steps []Step, err error) {
+	if err := plan.ModifiedError(); err != nil {
+		if !m.Force {
+			return nil, err
+		}
+		m.warn(err.Error())
+	}
+	if err := plan.PartialError(); err != nil {
+		if !m.Resume {
+			return nil, err
+		}
+		s, err := m.resumeStep(plan.Partial)
+		if err != nil {
+			return nil, err
+		}
+		m.log("resuming:", s.File, fmt.Sprintf("(from statement %d)", plan.Partial.Stmt+1))
+		applied, err := m.applyStep(s, plan.Partial)
+		if applied {
+			steps = append(steps, s)
+		}
+		if err != nil {
+			return steps, err
+		}
+		plan, err = plan.replan(ctx)
+		if err != nil {
+			return steps, err
+		}
+	}
+
+	if slices.ContainsFunc(plan.Steps, m.tracked) {
+		if err := createProgress(m.db); err != nil {
+			return steps, err
+		}
+	}
+	for _, s := range plan.Steps {
+		if err := ctx.Err(); err != nil {
+			return steps, err
+		}
+		m.log("applying:", s.File)
+		applied, err := m.applyStep(s, nil)
+		if applied {
+			steps = append(steps, s)
+		}
+		if err != nil {
+			return steps, err
+		}
+	}
+	return steps, nil
+}
+
+// resumeStep returns the step of the partially applied file.
+func (m *Migrator) resumeStep(p *Progress) (Step, error) {
+	migs, err := m.load()
+	if err != nil {
+		return Step{}, err
+	}
+	i, err := migs.FindNumber(p.Number)
+	if err != nil {
+		return Step{}, err
+	}
+	mig := migs[i]
+	switch p.File {
+	case mig.UpName():
+		return Step{Migration: mig, File: p.File}, nil
+	case mig.DownName():
+		return Step{Migration: mig, File: p.File, Down: true}, nil
+	}
+	return Step{}, fmt.Errorf("%w: %s", migrations.ErrNoMigration, p.File)
+}
+
+// applyStep applies the file of the step and records its checksum.
+// The up/down file runs in a transaction with Tx or 'migy:transaction' annotation,
+// or records the progress of each statement otherwise to resume from p.
+// It reports whether the changes of the file remain.
+func (m *Migrator) applyStep(s Step, p *Progress) (applied bool, err error) {
+	mig := s.Migration
+	var asserts []string
+	switch s.File {
+	case mig.UpName():
+		asserts = mig.UpAsserts
+	case mig.DownName():
+		asserts = mig.DownAsserts
+	}
+	tx, track := m.inTx(s), m.tracked(s)
+
+	var db sqlx.Ext = m.db
+	if tx {
+		m.warnImplicitCommits(s.File)
+		t, e := m.db.Beginx()
+		if e != nil {
+			return false, e
+		}
+		defer func() {
+			if err != nil {
+				t.Rollback()
+				applied = false
+			}
+		}()
+		db = t
+	}
+
+	if track {
+		err = applyTracked(m.db, m.fsys, mig, s.File, p)
+	} else {
+		err = mig.ApplyFS(db, m.fsys, s.File)
+	}
+	if err != nil {
+		return false, err
+	}
+	if s.File == mig.UpName() {
+		if err := recordChecksum(db, mig); err != nil {
+			return true, fmt.Errorf("%v: %w", s.File, err)
+		}
+	}
+	if m.Assert {
+		if err := migrations.Assert(db, asserts); err != nil {
+			return true, fmt.Errorf("%v: %w", s.File, err)
+		}
+	}
+	if t, ok := db.(*sqlx.Tx); ok {
+		if err := t.Commit(); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// inTx reports whether the up/down file of the step runs in a transaction.
+func (m *Migrator) inTx(s Step) bool {
+	switch s.File {
+	case s.Migration.UpName():
+		return m.Tx || s.Migration.UpTx
+	case s.Migration.DownName():
+		return m.Tx || s.Migration.DownTx
+	}
+	return false
+}
+
+// tracked reports whether the file of the step records the progress of each statement.
+func (m *Migrator) tracked(s Step) bool {
+	mig := s.Migration
+	return (s.File == mig.UpName() || s.File == mig.DownName()) && !m.inTx(s) && !migrations.IsGoFile(s.File)
+}
+
+// warnImplicitCommits warns the statements in the file which commit the transaction implicitly.
+func (m *Migrator) warnImplicitCommits(file string) {
+	if migrations.IsGoFile(file) {
+		return
+	}
+	input, err := fs.ReadFile(m.fsys, file)
+	if err != nil {
+		return // reported by applying the file
+	}
+	for _, stmt := range sqlfile.ImplicitCommits(input) {
+		line, _, _ := strings.Cut(stmt, "\n")
+		m.warn(fmt.Sprintf("%s: %q causes an implicit commit in the transaction", file, line))
+	}
+}
+
+// recordChecksum records the checksums of the applied migration files
+// if '_migrations' table has the checksum columns.
+func recordChecksum(db sqlx.Ext, m *migrations.Migration) error {
+	ok, err := migrations.HasChecksumColumns(db)
+	if err != nil || !ok {
+		return err
+	}
+	return migrations.RecordChecksum(db, m)
+}
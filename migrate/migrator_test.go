@@ -0,0 +1,229 @@
+package migrate_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/makiuchi-d/migy/internal/testutil"
+	"github.com/makiuchi-d/migy/migrate"
+)
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewDB("db")
+	defer db.Close()
+
+	m := migrate.New(db, filepath.Join("testdata", "apply"))
+
+	plan, err := m.Plan(ctx, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []string{"000000_init.all.sql", "000010_first.up.sql", "000020_second.up.sql"}
+	if d := cmp.Diff(exp, plan.Files()); d != "" {
+		t.Fatalf("plan 20:\n%v", d)
+	}
+	if plan.Current != -1 || plan.Target != 20 {
+		t.Fatalf("plan 20: current=%v target=%v", plan.Current, plan.Target)
+	}
+
+	steps, err := m.Up(ctx, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 3 || steps[2].Migration.Number != 20 || steps[2].Down {
+		t.Fatalf("up 20: %+v", steps)
+	}
+
+	_, err = m.Down(ctx, 30)
+	if !errors.Is(err, migrate.ErrDirection) {
+		t.Fatalf("down 30 must be ErrDirection: %v", err)
+	}
+
+	steps, err = m.Up(ctx, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 1 || steps[0].File != "000030_third.up.sql" {
+		t.Fatalf("up latest: %+v", steps)
+	}
+
+	plan, err = m.Plan(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp = []string{"000030_third.down.sql", "000020_second.down.sql"}
+	if d := cmp.Diff(exp, plan.Files()); d != "" {
+		t.Fatalf("plan 10:\n%v", d)
+	}
+	if plan.Current != 30 || !plan.Steps[0].Down {
+		t.Fatalf("plan 10: current=%v down=%v", plan.Current, plan.Steps[0].Down)
+	}
+
+	if _, err := m.Up(ctx, 10); !errors.Is(err, migrate.ErrDirection) {
+		t.Fatalf("up 10 must be ErrDirection: %v", err)
+	}
+	steps, err = m.Down(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 2 {
+		t.Fatalf("down 10: %+v", steps)
+	}
+
+	sts, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var applied []int
+	for _, st := range sts {
+		if st.IsApplied() {
+			applied = append(applied, st.Number)
+		}
+	}
+	if d := cmp.Diff([]int{0, 10}, applied); d != "" {
+		t.Fatalf("status:\n%v", d)
+	}
+}
+
+func TestMigratorWithoutDB(t *testing.T) {
+	ctx := context.Background()
+	m := migrate.New(nil, filepath.Join("testdata", "apply"))
+
+	sts, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sts) != 4 {
+		t.Fatalf("status: %+v", sts)
+	}
+
+	if _, err := m.Plan(ctx, -1); !errors.Is(err, migrate.ErrNoDatabase) {
+		t.Fatalf("plan must be ErrNoDatabase: %v", err)
+	}
+	if _, err := m.Up(ctx, -1); !errors.Is(err, migrate.ErrNoDatabase) {
+		t.Fatalf("up must be ErrNoDatabase: %v", err)
+	}
+}
+
+func TestMigratorFS(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join("testdata", "check", "success")
+	ents, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{}
+	for _, e := range ents {
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fsys[e.Name()] = &fstest.MapFile{Data: data}
+	}
+
+	r, err := migrate.NewFS(nil, fsys).Check(ctx, 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Diff != "" {
+		t.Fatalf("check 40: %v", r.Diff)
+	}
+
+	db := testutil.NewDB("db")
+	defer db.Close()
+	m := migrate.NewFS(db, fsys)
+	steps, err := m.Up(ctx, 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []string{"000030_insert.all.sql", "000040_alter_drop.up.sql"}
+	var files []string
+	for _, s := range steps {
+		files = append(files, s.File)
+	}
+	if d := cmp.Diff(exp, files); d != "" {
+		t.Fatal(d)
+	}
+}
+
+func TestMigratorLocked(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewDB("db")
+	defer db.Close()
+
+	m := migrate.New(db, filepath.Join("testdata", "apply"))
+	m.LockTimeout = 0
+
+	holder, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer holder.Close()
+	if _, err := holder.ExecContext(ctx, "SELECT GET_LOCK('migy.db', 0)"); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := m.Plan(ctx, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Apply(ctx, plan); !errors.Is(err, migrate.ErrLocked) {
+		t.Fatalf("apply must be ErrLocked: %v", err)
+	}
+	if _, err := m.Up(ctx, -1); !errors.Is(err, migrate.ErrLocked) {
+		t.Fatalf("up must be ErrLocked: %v", err)
+	}
+
+	if _, err := holder.ExecContext(ctx, "SELECT RELEASE_LOCK('migy.db')"); err != nil {
+		t.Fatal(err)
+	}
+	steps, err := m.Apply(ctx, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 4 {
+		t.Fatalf("apply: %+v", steps)
+	}
+}
+
+func TestMigratorApplyChanged(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewDB("db")
+	defer db.Close()
+
+	m := migrate.New(db, filepath.Join("testdata", "apply"))
+	if _, err := m.Up(ctx, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := m.Plan(ctx, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// another apply runs between the plan and the confirmation
+	if _, err := m.Up(ctx, 20); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Apply(ctx, plan); !errors.Is(err, migrate.ErrPlanChanged) {
+		t.Fatalf("apply must be ErrPlanChanged: %v", err)
+	}
+
+	plan, err = m.Plan(ctx, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps, err := m.Apply(ctx, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 1 || steps[0].File != "000030_third.up.sql" {
+		t.Fatalf("apply: %+v", steps)
+	}
+}
@@ -0,0 +1,125 @@
+package migrate_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/makiuchi-d/migy/internal/testutil"
+	"github.com/makiuchi-d/migy/migrate"
+)
+
+func TestOutOfOrder(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewDB("db")
+	defer db.Close()
+
+	m := migrate.New(db, filepath.Join("testdata", "apply"))
+
+	if _, err := m.Up(ctx, 10); err != nil {
+		t.Fatal(err)
+	}
+	// 000030 was applied before 000020 arrived
+	if err := m.Mark(ctx, []int{30}); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := m.Plan(ctx, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Steps) != 0 {
+		t.Fatalf("steps must be empty: %v", plan.Files())
+	}
+	if err := plan.OutOfOrderError(); !errors.Is(err, migrate.ErrOutOfOrder) {
+		t.Fatalf("OutOfOrderError must be ErrOutOfOrder: %v", err)
+	}
+
+	m.OutOfOrder = true
+	steps, err := m.Up(ctx, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 1 || steps[0].File != "000020_second.up.sql" {
+		t.Fatalf("up: %+v", steps)
+	}
+
+	plan, err = m.Plan(ctx, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Steps) != 0 || plan.OutOfOrderError() != nil {
+		t.Fatalf("plan after up: steps=%v out-of-order=%v", plan.Files(), plan.OutOfOrder)
+	}
+
+	// downgrade in reverse order of application
+	for _, q := range []string{
+		"UPDATE _migrations SET applied = '2025-01-01 00:00:00' WHERE id = 30",
+		"UPDATE _migrations SET applied = '2025-01-02 00:00:00' WHERE id = 20",
+	} {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	steps, err = m.Down(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []string
+	for _, s := range steps {
+		files = append(files, s.File)
+	}
+	exp := []string{"000020_second.down.sql", "000030_third.down.sql"}
+	if d := cmp.Diff(exp, files); d != "" {
+		t.Fatalf("down:\n%v", d)
+	}
+}
+
+func TestOutOfOrderDown(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewDB("db")
+	defer db.Close()
+
+	m := migrate.New(db, filepath.Join("testdata", "apply"))
+
+	if _, err := m.Up(ctx, 10); err != nil {
+		t.Fatal(err)
+	}
+	// 000030 was applied before 000020 arrived
+	if err := m.Mark(ctx, []int{30}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the unapplied 000020 is reported and not rolled back
+	plan, err := m.Plan(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := plan.OutOfOrderError(); !errors.Is(err, migrate.ErrOutOfOrder) {
+		t.Fatalf("OutOfOrderError must be ErrOutOfOrder: %v", err)
+	}
+	exp := []string{"000030_third.down.sql", "000010_first.down.sql"}
+	if d := cmp.Diff(exp, plan.Files()); d != "" {
+		t.Fatalf("plan 0:\n%v", d)
+	}
+
+	if _, err := m.Down(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	sts, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var applied []int
+	for _, st := range sts {
+		if st.IsApplied() {
+			applied = append(applied, st.Number)
+		}
+	}
+	if d := cmp.Diff([]int{0}, applied); d != "" {
+		t.Fatalf("status:\n%v", d)
+	}
+}
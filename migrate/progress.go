@@ -0,0 +1,129 @@
+package migrate
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/makiuchi-d/migy/migrations"
+	"github.com/makiuchi-d/migy/sqlfile"
+)
+
+var ErrPartiallyApplied = errors.New("file partially applied")
+
+// Progress is the statement-level progress of the file being applied.
+// It remains in '_migrations_progress' table when a statement of the file failed.
+type Progress struct {
+	Number int    `db:"id"`
+	File   string `db:"file"`
+	Stmt   int    `db:"stmt"` // number of executed statements
+	Hash   string `db:"hash"` // hash of the executed statements
+}
+
+const createProgressTable = `CREATE TABLE IF NOT EXISTS _migrations_progress (
+  id   INTEGER NOT NULL,
+  file VARCHAR(255) NOT NULL,
+  stmt INTEGER NOT NULL,
+  hash VARCHAR(64) NOT NULL,
+  PRIMARY KEY (id)
+)`
+
+// loadProgress returns the progress of the partially applied file, or nil if nothing.
+func loadProgress(db *sqlx.DB) (*Progress, error) {
+	var s string
+	err := db.Get(&s, "SHOW TABLES LIKE '_migrations_progress'")
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// the row of no executed statements is nothing applied
+	var p Progress
+	err = db.Get(&p, "SELECT id, file, stmt, hash FROM _migrations_progress WHERE stmt > 0 ORDER BY id LIMIT 1")
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// createProgress creates '_migrations_progress' table if not exists.
+// It is called once before applying the files, since CREATE TABLE commits the transaction implicitly.
+func createProgress(db *sqlx.DB) error {
+	var s string
+	err := db.Get(&s, "SHOW TABLES LIKE '_migrations_progress'")
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	_, err = db.Exec(createProgressTable)
+	return err
+}
+
+// stmtHash returns the hash of the statements following the hash of the previous ones.
+func stmtHash(prev, stmt string) string {
+	h := sha256.Sum256([]byte(prev + "\n" + stmt))
+	return hex.EncodeToString(h[:])
+}
+
+// applyTracked applies the statements of the file recording the progress after each statement.
+// The statements are read one by one, and the file can be gzip-compressed.
+// It skips the statements executed in the progress p if not nil.
+func applyTracked(db *sqlx.DB, fsys fs.FS, mig *migrations.Migration, file string, p *Progress) error {
+	f, err := fsys.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	s, err := sqlfile.NewFileScanner(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+
+	if p == nil {
+		p = &Progress{Number: mig.Number, File: file}
+		// replace the row left by the file failed at the first statement
+		const q = "REPLACE INTO _migrations_progress (id, file, stmt, hash) VALUES (?, ?, 0, '')"
+		if _, err := db.Exec(q, p.Number, p.File); err != nil {
+			return err
+		}
+	}
+
+	i, hash := 0, ""
+	for s.Scan() {
+		pos, stmt := s.Pos(), s.Stmt()
+		if i < p.Stmt {
+			hash = stmtHash(hash, stmt)
+			i++
+			if i == p.Stmt && hash != p.Hash {
+				return fmt.Errorf("%w: %s: executed statements changed", ErrModified, file)
+			}
+			continue
+		}
+		if _, err := db.Exec(stmt); err != nil {
+			return &sqlfile.Error{File: file, Pos: pos, Index: i + 1, Stmt: stmt, Err: err}
+		}
+		hash = stmtHash(hash, stmt)
+		i++
+		const q = "UPDATE _migrations_progress SET stmt = ?, hash = ? WHERE id = ?"
+		if _, err := db.Exec(q, i, hash, p.Number); err != nil {
+			return err
+		}
+	}
+	if err := s.Err(); err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	if i < p.Stmt {
+		return fmt.Errorf("%w: %s: executed statements changed", ErrModified, file)
+	}
+
+	_, err = db.Exec("DELETE FROM _migrations_progress WHERE id = ?", p.Number)
+	return err
+}
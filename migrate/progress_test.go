@@ -0,0 +1,167 @@
+package migrate_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/makiuchi-d/migy/dbstate"
+	"github.com/makiuchi-d/migy/internal/testutil"
+	"github.com/makiuchi-d/migy/migrate"
+)
+
+func TestApplyResume(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join("testdata", "gofunc")
+	fsys := fstest.MapFS{}
+	for _, name := range []string{"000000_init.all.sql", "000010_create_users.up.sql", "000010_create_users.down.sql"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fsys[name] = &fstest.MapFile{Data: data}
+	}
+	const up20 = "INSERT INTO _migrations (id, title, applied) VALUES (20, 'add_user', now());\n" +
+		"INSERT INTO users (id, name) VALUES (3, 'user3');\n"
+	fsys["000020_add_user.up.sql"] = &fstest.MapFile{Data: []byte(up20 + "UPDATE nosuch SET name = 'x';\n")}
+	fsys["000020_add_user.down.sql"] = &fstest.MapFile{Data: []byte("CALL _migration_exists(20);\nDELETE FROM _migrations WHERE id = 20;\nDELETE FROM users WHERE id = 3;\n")}
+
+	db := testutil.NewDB("db")
+	defer db.Close()
+	m := migrate.NewFS(db, fsys)
+
+	steps, err := m.Up(ctx, -1)
+	if err == nil || len(steps) != 2 {
+		t.Fatalf("up must fail at 000020: steps=%v err=%v", len(steps), err)
+	}
+
+	plan, err := m.Plan(ctx, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := plan.Partial; p == nil || p.Number != 20 || p.File != "000020_add_user.up.sql" || p.Stmt != 2 {
+		t.Fatalf("partial: %+v", p)
+	}
+	if _, err := m.Up(ctx, -1); !errors.Is(err, migrate.ErrPartiallyApplied) {
+		t.Fatalf("must be ErrPartiallyApplied: %v", err)
+	}
+
+	// executed statements must not be changed
+	m.Resume = true
+	fsys["000020_add_user.up.sql"].Data = []byte("INSERT INTO users (id, name) VALUES (4, 'user4');\n")
+	if _, err := m.Up(ctx, -1); !errors.Is(err, migrate.ErrModified) {
+		t.Fatalf("must be ErrModified: %v", err)
+	}
+
+	fsys["000020_add_user.up.sql"].Data = []byte(up20 + "UPDATE users SET name = 'resumed' WHERE id = 3;\n")
+	steps, err = m.Up(ctx, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 1 || steps[0].File != "000020_add_user.up.sql" {
+		t.Fatalf("steps: %+v", steps)
+	}
+
+	sdb := sqlx.NewDb(db, "mysql")
+	recs, err := dbstate.GetRecords(sdb, "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := recs.Rows[len(recs.Rows)-1].String(); s != "(3, 'resumed', '')" {
+		t.Fatalf("resumed row: %v", s)
+	}
+	recs, err = dbstate.GetRecords(sdb, "_migrations_progress")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs.Rows) != 0 {
+		t.Fatalf("progress remains: %v", recs.Rows)
+	}
+}
+
+func TestApplyFailFirstStatement(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join("testdata", "gofunc")
+	fsys := fstest.MapFS{}
+	for _, name := range []string{"000000_init.all.sql", "000010_create_users.up.sql", "000010_create_users.down.sql"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fsys[name] = &fstest.MapFile{Data: data}
+	}
+	fsys["000020_add_user.up.sql"] = &fstest.MapFile{Data: []byte("UPDATE nosuch SET name = 'x';\n")}
+	fsys["000020_add_user.down.sql"] = &fstest.MapFile{Data: []byte("CALL _migration_exists(20);\nDELETE FROM _migrations WHERE id = 20;\n")}
+
+	db := testutil.NewDB("db")
+	defer db.Close()
+	m := migrate.NewFS(db, fsys)
+
+	if _, err := m.Up(ctx, -1); err == nil {
+		t.Fatal("up must fail at 000020")
+	}
+
+	// nothing of the file is applied
+	plan, err := m.Plan(ctx, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Partial != nil || plan.Current != 10 {
+		t.Fatalf("plan: current=%v partial=%+v", plan.Current, plan.Partial)
+	}
+
+	fsys["000020_add_user.up.sql"].Data = []byte("INSERT INTO _migrations (id, title, applied) VALUES (20, 'add_user', now());\n")
+	steps, err := m.Apply(ctx, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 1 || steps[0].File != "000020_add_user.up.sql" {
+		t.Fatalf("steps: %+v", steps)
+	}
+}
+
+func TestApplyTrackedGzip(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join("testdata", "gofunc")
+	fsys := fstest.MapFS{}
+	for _, name := range []string{"000000_init.all.sql", "000010_create_users.up.sql", "000010_create_users.down.sql"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fsys[name] = &fstest.MapFile{Data: data}
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte("INSERT INTO _migrations (id, title, applied) VALUES (20, 'add_user', now());\n" +
+		"INSERT INTO users (id, name) VALUES (3, 'user3');\n"))
+	zw.Close()
+	fsys["000020_add_user.up.sql"] = &fstest.MapFile{Data: buf.Bytes()}
+	fsys["000020_add_user.down.sql"] = &fstest.MapFile{Data: []byte("CALL _migration_exists(20);\nDELETE FROM _migrations WHERE id = 20;\n")}
+
+	db := testutil.NewDB("db")
+	defer db.Close()
+	m := migrate.NewFS(db, fsys)
+
+	steps, err := m.Up(ctx, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 3 || steps[2].File != "000020_add_user.up.sql" {
+		t.Fatalf("steps: %+v", steps)
+	}
+	recs, err := dbstate.GetRecords(sqlx.NewDb(db, "mysql"), "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := recs.Rows[len(recs.Rows)-1].String(); s != "(3, 'user3', '')" {
+		t.Fatalf("inserted row: %v", s)
+	}
+}
@@ -0,0 +1,95 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/makiuchi-d/migy/migrations"
+)
+
+var ErrRedoSteps = errors.New("invalid number of steps to redo")
+
+// RedoPlan returns the steps to roll back the latest n migrations in the history and apply them again.
+// The latest are counted in order of application, so the unapplied migrations between them are left as they are.
+// Plan.Modified is the migrations whose down files have been modified since they were applied,
+// while the modified up files are what the redo applies.
+// It returns ErrPartiallyApplied if a file is partially applied.
+func (m *Migrator) RedoPlan(ctx context.Context, n int) (*Plan, error) {
+	migs, hists, _, err := m.histories()
+	if err != nil {
+		return nil, err
+	}
+	cur := migrations.Histories(hists).CurrentNum()
+	if cur < 0 {
+		return nil, ErrNotInitialized
+	}
+
+	// the latest n migrations in order of application
+	hs := slices.Clone(hists)
+	slices.SortStableFunc(hs, func(a, b migrations.History) int {
+		if c := a.Applied.Compare(b.Applied); c != 0 {
+			return c
+		}
+		return a.Id - b.Id
+	})
+	if n < 1 || n >= len(hs) {
+		return nil, fmt.Errorf("%w: %d steps from %06d", ErrRedoSteps, n, cur)
+	}
+
+	var downs, ups []string
+	var mods []*migrations.Migration
+	for _, h := range hs[len(hs)-n:] {
+		i, err := migs.FindNumber(h.Id)
+		if err != nil {
+			return nil, err
+		}
+		mig := migs[i]
+		if !mig.UpDown {
+			return nil, fmt.Errorf("%w: %06d has no down file", ErrRedoSteps, h.Id)
+		}
+		downs = slices.Insert(downs, 0, mig.DownName())
+		ups = append(ups, mig.UpName())
+		if h.DownSum != "" && h.DownSum != mig.DownSum {
+			mods = append(mods, mig)
+		}
+	}
+	partial, err := loadProgress(m.db)
+	if err != nil {
+		return nil, err
+	}
+	plan := &Plan{Current: cur, Target: cur, Steps: buildSteps(migs, append(downs, ups...)), Modified: mods, Partial: partial}
+	if err := plan.PartialError(); err != nil {
+		return nil, err
+	}
+	plan.replan = func(ctx context.Context) (*Plan, error) { return m.RedoPlan(ctx, n) }
+	return plan, nil
+}
+
+// Redo rolls back the latest n migrations and applies them again.
+// It refuses to run if the down files have been modified since applied unless Force,
+// or if a file is partially applied (see RedoPlan).
+// It returns the applied steps even if an error occurs.
+func (m *Migrator) Redo(ctx context.Context, n int) (steps []Step, err error) {
+	if m.db == nil {
+		return nil, ErrNoDatabase
+	}
+	if !m.NoLock {
+		lock, e := acquireLock(ctx, m.db, m.LockTimeout)
+		if e != nil {
+			return nil, e
+		}
+		defer func() {
+			if e := lock.Release(); e != nil && err == nil {
+				err = e
+			}
+		}()
+	}
+
+	plan, err := m.RedoPlan(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+	return m.run(ctx, plan)
+}
@@ -0,0 +1,134 @@
+package migrate_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/makiuchi-d/migy/internal/testutil"
+	"github.com/makiuchi-d/migy/migrate"
+)
+
+func TestRedo(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	if err := os.CopyFS(dir, os.DirFS(filepath.Join("testdata", "apply"))); err != nil {
+		t.Fatal(err)
+	}
+	db := testutil.NewDB("db")
+	defer db.Close()
+
+	m := migrate.New(db, dir)
+
+	if _, err := m.Up(ctx, -1); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := m.RedoPlan(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []string{"000030_third.down.sql", "000020_second.down.sql", "000020_second.up.sql", "000030_third.up.sql"}
+	if d := cmp.Diff(exp, plan.Files()); d != "" {
+		t.Fatalf("redo plan 2:\n%v", d)
+	}
+
+	_, err = m.RedoPlan(ctx, 4)
+	if !errors.Is(err, migrate.ErrRedoSteps) {
+		t.Fatalf("redo 4 must be ErrRedoSteps: %v", err)
+	}
+
+	appendFile := func(name string) {
+		f, err := os.OpenFile(filepath.Join(dir, name), os.O_APPEND|os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.WriteString("SELECT 1;\n")
+		f.Close()
+	}
+
+	// the modified up file is applied again
+	appendFile("000030_third.up.sql")
+	steps, err := m.Redo(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 2 {
+		t.Fatalf("redo 1: %+v", steps)
+	}
+	plan, err = m.Plan(ctx, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Modified) != 0 {
+		t.Fatalf("checksum must be updated: %v", plan.Modified)
+	}
+
+	appendFile("000030_third.down.sql")
+	_, err = m.Redo(ctx, 1)
+	if !errors.Is(err, migrate.ErrModified) {
+		t.Fatalf("redo with modified down file must be ErrModified: %v", err)
+	}
+	m.Force = true
+	if _, err := m.Redo(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRedoOutOfOrder(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewDB("db")
+	defer db.Close()
+
+	m := migrate.New(db, filepath.Join("testdata", "apply"))
+
+	if _, err := m.Up(ctx, 10); err != nil {
+		t.Fatal(err)
+	}
+	// 000030 was applied before 000020 arrived
+	if err := m.Mark(ctx, []int{30}); err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []string{
+		"UPDATE _migrations SET applied = '2025-01-01 00:00:00' WHERE id = 0",
+		"UPDATE _migrations SET applied = '2025-01-02 00:00:00' WHERE id = 10",
+		"UPDATE _migrations SET applied = '2025-01-03 00:00:00' WHERE id = 30",
+	} {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	plan, err := m.RedoPlan(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []string{"000030_third.down.sql", "000010_first.down.sql", "000010_first.up.sql", "000030_third.up.sql"}
+	if d := cmp.Diff(exp, plan.Files()); d != "" {
+		t.Fatalf("redo plan 2:\n%v", d)
+	}
+	if _, err := m.RedoPlan(ctx, 3); !errors.Is(err, migrate.ErrRedoSteps) {
+		t.Fatalf("redo 3 must be ErrRedoSteps: %v", err)
+	}
+
+	if _, err := m.Redo(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	sts, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var applied []int
+	for _, st := range sts {
+		if st.IsApplied() {
+			applied = append(applied, st.Number)
+		}
+	}
+	if d := cmp.Diff([]int{0, 10, 30}, applied); d != "" {
+		t.Fatalf("status:\n%v", d)
+	}
+}
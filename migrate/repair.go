@@ -0,0 +1,201 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/makiuchi-d/migy/dbstate"
+	"github.com/makiuchi-d/migy/migrations"
+)
+
+var (
+	ErrAlreadyApplied = errors.New("already applied")
+	ErrNotApplied     = errors.New("not applied")
+)
+
+// Repair is a change of the history in '_migrations' table without executing the migrations.
+type Repair struct {
+	Mark      []int // numbers to record as applied
+	Unmark    []int // numbers to remove from the history
+	FixTitles bool  // rewrite the titles which do not match the migration files
+}
+
+// RepairPlan is the validated repair and its statements.
+type RepairPlan struct {
+	Repair
+	Stmts []string // SQL statements to be executed, for a preview
+}
+
+// PlanRepair validates the repair against the current history and returns the statements for a preview.
+// The plan is executed by ApplyRepair.
+func (m *Migrator) PlanRepair(ctx context.Context, r Repair) (*RepairPlan, error) {
+	stmts, err := m.repairSQL(r)
+	if err != nil {
+		return nil, err
+	}
+	return &RepairPlan{Repair: r, Stmts: stmts}, nil
+}
+
+// ApplyRepair executes the plan in a transaction.
+// It takes the lock and validates the repair again, then refuses to run with ErrPlanChanged
+// if the statements differ from the plan.
+func (m *Migrator) ApplyRepair(ctx context.Context, plan *RepairPlan) error {
+	return m.repair(ctx, plan.Repair, plan)
+}
+
+// Mark records the migrations as applied without executing them.
+func (m *Migrator) Mark(ctx context.Context, nums []int) error {
+	return m.repair(ctx, Repair{Mark: nums}, nil)
+}
+
+// Unmark removes the migrations from the history without executing them.
+func (m *Migrator) Unmark(ctx context.Context, nums []int) error {
+	return m.repair(ctx, Repair{Unmark: nums}, nil)
+}
+
+// FixTitles rewrites the titles in the history which do not match the titles of the migration files.
+func (m *Migrator) FixTitles(ctx context.Context) error {
+	return m.repair(ctx, Repair{FixTitles: true}, nil)
+}
+
+// repair validates and executes the repair under the lock.
+// If plan is not nil, the statements must be equal to the planned ones.
+func (m *Migrator) repair(ctx context.Context, r Repair, plan *RepairPlan) (err error) {
+	if m.db == nil {
+		return ErrNoDatabase
+	}
+	if !m.NoLock {
+		lock, e := acquireLock(ctx, m.db, m.LockTimeout)
+		if e != nil {
+			return e
+		}
+		defer func() {
+			if e := lock.Release(); e != nil && err == nil {
+				err = e
+			}
+		}()
+	}
+
+	stmts, err := m.repairSQL(r)
+	if err != nil {
+		return err
+	}
+	if plan != nil && !slices.Equal(stmts, plan.Stmts) {
+		return fmt.Errorf("%w: %v -> %v", ErrPlanChanged, plan.Stmts, stmts)
+	}
+
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, q := range stmts {
+		m.log("executing:", q)
+		if _, err := tx.ExecContext(ctx, q); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// repairSQL validates the repair and returns the statements
+// in the order of fixing titles, unmarking and marking.
+func (m *Migrator) repairSQL(r Repair) ([]string, error) {
+	migs, hists, sum, err := m.histories()
+	if err != nil {
+		return nil, err
+	}
+
+	var stmts []string
+	if r.FixTitles {
+		for st := range migrations.BuildStatus(migs, hists) {
+			if st.DBTitle != "" {
+				stmts = append(stmts, fmt.Sprintf("UPDATE _migrations SET title = %s WHERE id = %d;", quote(st.Title), st.Number))
+			}
+		}
+	}
+
+	for _, n := range sortedNumbers(r.Unmark) {
+		if hists.find(n) == nil {
+			return nil, fmt.Errorf("%w: %06d", ErrNotApplied, n)
+		}
+		stmts = append(stmts, fmt.Sprintf("DELETE FROM _migrations WHERE id = %d;", n))
+	}
+
+	for _, n := range sortedNumbers(r.Mark) {
+		i, err := migs.FindNumber(n)
+		if err != nil {
+			return nil, err
+		}
+		if hists.find(n) != nil {
+			return nil, fmt.Errorf("%w: %06d", ErrAlreadyApplied, n)
+		}
+		mig := migs[i]
+		if sum {
+			stmts = append(stmts, fmt.Sprintf(
+				"INSERT INTO _migrations (id, applied, title, up_checksum, down_checksum) VALUES (%d, now(), %s, %s, %s);",
+				mig.Number, quote(mig.Title), quoteOrNull(mig.UpSum), quoteOrNull(mig.DownSum)))
+		} else {
+			stmts = append(stmts, fmt.Sprintf(
+				"INSERT INTO _migrations (id, applied, title) VALUES (%d, now(), %s);", mig.Number, quote(mig.Title)))
+		}
+	}
+	return stmts, nil
+}
+
+type histories migrations.Histories
+
+func (hs histories) find(num int) *migrations.History {
+	for i := range hs {
+		if hs[i].Id == num {
+			return &hs[i]
+		}
+	}
+	return nil
+}
+
+// histories returns the migration files and the history of the database,
+// and whether '_migrations' table has the checksum columns.
+func (m *Migrator) histories() (migrations.Migrations, histories, bool, error) {
+	if m.db == nil {
+		return nil, nil, false, ErrNoDatabase
+	}
+	if err := dbstate.HasMigrationTable(m.db); err != nil {
+		return nil, nil, false, err
+	}
+	sum, err := migrations.HasChecksumColumns(m.db)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	hists, err := migrations.LoadHistories(m.db)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	migs, err := m.load()
+	if err != nil {
+		return nil, nil, false, err
+	}
+	return migs, histories(hists), sum, nil
+}
+
+func sortedNumbers(nums []int) []int {
+	ns := slices.Clone(nums)
+	slices.Sort(ns)
+	return slices.Compact(ns)
+}
+
+// quote returns the SQL string literal of s.
+func quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func quoteOrNull(s string) string {
+	if s == "" {
+		return "NULL"
+	}
+	return quote(s)
+}
@@ -0,0 +1,98 @@
+package migrate_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/makiuchi-d/migy/internal/testutil"
+	"github.com/makiuchi-d/migy/migrate"
+	"github.com/makiuchi-d/migy/migrations"
+)
+
+func TestRepair(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewDB("db")
+	defer db.Close()
+
+	m := migrate.New(db, filepath.Join("testdata", "apply"))
+
+	if _, err := m.Up(ctx, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := m.PlanRepair(ctx, migrate.Repair{Mark: []int{30, 20, 30}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Stmts) != 2 {
+		t.Fatalf("mark: %v", plan.Stmts)
+	}
+	if err := m.ApplyRepair(ctx, plan); err != nil {
+		t.Fatal(err)
+	}
+	p, err := m.Plan(ctx, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Current != 30 || len(p.Steps) != 0 || len(p.Modified) != 0 {
+		t.Fatalf("plan after mark: current=%v steps=%v modified=%v", p.Current, p.Files(), p.Modified)
+	}
+	if err := m.ApplyRepair(ctx, plan); !errors.Is(err, migrate.ErrAlreadyApplied) {
+		t.Fatalf("applying again must be ErrAlreadyApplied: %v", err)
+	}
+
+	if err := m.Mark(ctx, []int{20}); !errors.Is(err, migrate.ErrAlreadyApplied) {
+		t.Fatalf("mark 20 must be ErrAlreadyApplied: %v", err)
+	}
+	if err := m.Mark(ctx, []int{40}); !errors.Is(err, migrations.ErrNoMigration) {
+		t.Fatalf("mark 40 must be ErrNoMigration: %v", err)
+	}
+
+	plan, err = m.PlanRepair(ctx, migrate.Repair{Unmark: []int{30}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff([]string{"DELETE FROM _migrations WHERE id = 30;"}, plan.Stmts); d != "" {
+		t.Fatalf("unmark:\n%v", d)
+	}
+	if err := m.Unmark(ctx, []int{30}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Unmark(ctx, []int{30}); !errors.Is(err, migrate.ErrNotApplied) {
+		t.Fatalf("unmark 30 must be ErrNotApplied: %v", err)
+	}
+
+	if _, err := db.Exec("UPDATE _migrations SET title = 'it''s' WHERE id = 10"); err != nil {
+		t.Fatal(err)
+	}
+	plan, err = m.PlanRepair(ctx, migrate.Repair{FixTitles: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff([]string{"UPDATE _migrations SET title = 'first' WHERE id = 10;"}, plan.Stmts); d != "" {
+		t.Fatalf("fix titles:\n%v", d)
+	}
+
+	// the history changes between the plan and the apply
+	if _, err := db.Exec("UPDATE _migrations SET title = 'x' WHERE id = 20"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.ApplyRepair(ctx, plan); !errors.Is(err, migrate.ErrPlanChanged) {
+		t.Fatalf("apply must be ErrPlanChanged: %v", err)
+	}
+
+	if err := m.FixTitles(ctx); err != nil {
+		t.Fatal(err)
+	}
+	plan, err = m.PlanRepair(ctx, migrate.Repair{FixTitles: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Stmts) != 0 {
+		t.Fatalf("fix titles after repair: %v", plan.Stmts)
+	}
+}
@@ -0,0 +1,231 @@
+package migrate
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/makiuchi-d/testdb"
+
+	"github.com/makiuchi-d/migy/migrations"
+	"github.com/makiuchi-d/migy/sqlfile"
+)
+
+// cacheVersion invalidates all cached states when the dump format changes.
+const cacheVersion = "migy-cache-v1"
+
+// cacheMaxAge is the age of the cached states to be pruned since they were last used.
+const cacheMaxAge = 30 * 24 * time.Hour
+
+// DefaultCacheDir returns "migy" in the user cache directory, or empty if it is unavailable.
+func DefaultCacheDir() string {
+	d, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(d, "migy")
+}
+
+// OpenSandbox returns a temporary in-memory database with the migration files applied.
+// When CacheDir is set, it restores the longest cached prefix of the files and replays only the rest,
+// then caches the resulting state.
+func (m *Migrator) OpenSandbox(files []string) (*sqlx.DB, error) {
+	return m.openSandbox(files, m.log)
+}
+
+func (m *Migrator) openSandbox(files []string, log func(...any)) (*sqlx.DB, error) {
+	db := newSandboxDB("db")
+	if m.CacheDir == "" || len(files) == 0 {
+		if err := m.applyFiles(db, files, log); err != nil {
+			db.Close()
+			return nil, err
+		}
+		return db, nil
+	}
+
+	keys, err := cacheKeys(m.fsys, files)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	start := 0
+	for i := len(keys) - 1; i >= 0; i-- {
+		p := m.cachePath(keys[i])
+		if _, err := os.Stat(p); err != nil {
+			continue
+		}
+		log("restoring:", files[i], "(cached)")
+		now := time.Now()
+		os.Chtimes(p, now, now) // keep the used state from being pruned
+		if err := sqlfile.Apply(db, p); err != nil {
+			// broken cache entry: drop it and start over
+			m.warn("broken cache: " + p + ": " + err.Error())
+			os.Remove(p)
+			db.Close()
+			db = newSandboxDB("db")
+			continue
+		}
+		start = i + 1
+		break
+	}
+
+	if err := m.applyFiles(db, files[start:], log); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if start < len(files) && len(keys) == len(files) {
+		p := m.cachePath(keys[len(keys)-1])
+		if err := storeCache(p, db); err != nil {
+			m.warn("failed to cache the state: " + err.Error())
+		}
+		if err := pruneCache(filepath.Dir(p), cacheMaxAge); err != nil {
+			m.warn("failed to prune the cache: " + err.Error())
+		}
+	}
+	return db, nil
+}
+
+// sandboxMu serializes creating the in-memory databases,
+// since go-mysql-server initializes its global status variables on each engine.
+var sandboxMu sync.Mutex
+
+func newSandboxDB(name string) *sqlx.DB {
+	sandboxMu.Lock()
+	defer sandboxMu.Unlock()
+	return sqlx.NewDb(testdb.New(name), "mysql")
+}
+
+// cloneSandbox returns a new temporary database with the same state as the db.
+func cloneSandbox(db *sqlx.DB) (*sqlx.DB, error) {
+	var buf bytes.Buffer
+	if err := sqlfile.Dump(&buf, db); err != nil {
+		return nil, err
+	}
+	clone := newSandboxDB("db")
+	for q := range sqlfile.Parse(buf.Bytes()) {
+		if _, err := clone.Exec(q); err != nil {
+			clone.Close()
+			return nil, err
+		}
+	}
+	return clone, nil
+}
+
+// applyFiles applies the files including the Go migrations.
+func (m *Migrator) applyFiles(db *sqlx.DB, files []string, log func(...any)) error {
+	if len(files) == 0 {
+		return nil
+	}
+	migs, err := m.load()
+	if err != nil {
+		return err
+	}
+	for i, s := range buildSteps(migs, files) {
+		log("applying:", files[i])
+		if s.Migration == nil {
+			err = sqlfile.ApplyFS(db, m.fsys, files[i])
+		} else {
+			err = s.Migration.ApplyFS(db, m.fsys, s.File)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cacheKeys returns the keys of the states after applying each file.
+// Each key depends on the names and contents of all files up to the one.
+// The keys end before the first Go migration since its content is unknown.
+func cacheKeys(fsys fs.FS, files []string) ([]string, error) {
+	keys := make([]string, 0, len(files))
+	prev := cacheVersion
+	for _, file := range files {
+		if migrations.IsGoFile(file) {
+			break
+		}
+		h := sha256.New()
+		io.WriteString(h, prev+"\n"+file+"\n")
+		f, err := fsys.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		prev = hex.EncodeToString(h.Sum(nil))
+		keys = append(keys, prev)
+	}
+	return keys, nil
+}
+
+func (m *Migrator) cachePath(key string) string {
+	d := m.CacheDir
+	if !filepath.IsAbs(d) && m.dir != "" {
+		d = filepath.Join(m.dir, d)
+	}
+	return filepath.Join(d, key+".sql")
+}
+
+// storeCache writes the dump of the db to the file atomically.
+func storeCache(path string, db *sqlx.DB) error {
+	d := filepath.Dir(path)
+	if err := os.MkdirAll(d, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(d, "tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := sqlfile.Dump(f, db); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	return nil
+}
+
+// pruneCache removes the cached states and the temporary files in the dir
+// which have not been used for maxAge.
+func pruneCache(dir string, maxAge time.Duration) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !(strings.HasSuffix(name, ".sql") || strings.HasPrefix(name, "tmp-")) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue // removed by another process
+		}
+		if time.Since(info.ModTime()) > maxAge {
+			if err := os.Remove(filepath.Join(dir, name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package migrate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/makiuchi-d/migy/dbstate"
+)
+
+func TestOpenSandbox(t *testing.T) {
+	dir := filepath.Join("testdata", "snapshot")
+	files := []string{"000000_init.all.sql", "000010_create_users.up.sql", "000020_alter_users.up.sql"}
+
+	m := New(nil, dir)
+	m.CacheDir = t.TempDir()
+
+	open := func(files []string) ([]string, *dbstate.Snapshot) {
+		var logs []string
+		db, err := m.openSandbox(files, func(a ...any) { logs = append(logs, fmt.Sprint(a...)) })
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		ss, err := dbstate.TakeSnapshot(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return logs, ss
+	}
+
+	logs, _ := open(files[:2])
+	exp := []string{"applying:000000_init.all.sql", "applying:000010_create_users.up.sql"}
+	if d := cmp.Diff(exp, logs); d != "" {
+		t.Fatalf("first run:\n%v", d)
+	}
+
+	logs, ss := open(files)
+	exp = []string{"restoring:000010_create_users.up.sql(cached)", "applying:000020_alter_users.up.sql"}
+	if d := cmp.Diff(exp, logs); d != "" {
+		t.Fatalf("second run:\n%v", d)
+	}
+
+	logs, _ = open(files)
+	exp = []string{"restoring:000020_alter_users.up.sql(cached)"}
+	if d := cmp.Diff(exp, logs); d != "" {
+		t.Fatalf("third run:\n%v", d)
+	}
+
+	// the cached state must be the same as the replayed one
+	m.CacheDir = ""
+	db, err := m.OpenSandbox(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	diff, err := dbstate.Diff(db, ss, map[string][]string{"_migrations": {"applied"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff != "" {
+		t.Errorf("cached state differs:\n%v", diff)
+	}
+}
+
+func TestPruneCache(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-cacheMaxAge - time.Hour)
+	for _, name := range []string{"stale.sql", "fresh.sql", "tmp-123", "other.txt"} {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if name != "fresh.sql" {
+			if err := os.Chtimes(p, old, old); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if err := pruneCache(dir, cacheMaxAge); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if d := cmp.Diff([]string{"fresh.sql", "other.txt"}, names); d != "" {
+		t.Fatal(d)
+	}
+}
@@ -0,0 +1,24 @@
+-- Generated by migy (https://github.com/makiuchi-d/migy)
+
+CREATE TABLE _migrations (
+   id            INTEGER NOT NULL,
+   applied       DATETIME,
+   title         VARCHAR(255),
+   up_checksum   CHAR(64),
+   down_checksum CHAR(64),
+   PRIMARY KEY (id)
+);
+
+INSERT INTO _migrations (id, applied, title) VALUES (0, now(), 'init');
+
+DELIMITER //
+
+CREATE PROCEDURE _migration_exists(IN input_id INTEGER)
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM _migrations WHERE id = input_id) THEN
+    SIGNAL SQLSTATE '45000'
+      SET MESSAGE_TEXT = 'migration not found';
+  END IF;
+END//
+
+DELIMITER ;
@@ -0,0 +1,3 @@
+-- Generated by migy (https://github.com/makiuchi-d/migy)
+INSERT INTO _migrations (id, title, applied) VALUES (30, 'third', now());
+-- Write your forward migration SQL statements below.
@@ -0,0 +1,6 @@
+-- Generated by migy (https://github.com/makiuchi-d/migy)
+CALL _migration_exists(10);
+DELETE FROM _migrations WHERE id = 10;
+-- Write your rollback SQL statements below.
+
+DROP TABLE `users`;
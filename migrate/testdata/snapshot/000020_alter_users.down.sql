@@ -0,0 +1,6 @@
+-- Generated by migy (https://github.com/makiuchi-d/migy)
+CALL _migration_exists(20);
+DELETE FROM _migrations WHERE id = 20;
+-- Write your rollback SQL statements below.
+
+ALTER TABLE `users` DROP COLUMN `email`;
@@ -0,0 +1,7 @@
+-- Generated by migy (https://github.com/makiuchi-d/migy)
+INSERT INTO _migrations (id, title, applied) VALUES (20, 'backfill', now());
+-- Write your forward migration SQL statements below.
+-- migy:transaction
+
+UPDATE `users` SET `hash` = 'x';
+ALTER TABLE `users` ADD COLUMN `memo` text;
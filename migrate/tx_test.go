@@ -0,0 +1,72 @@
+package migrate_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/makiuchi-d/migy/internal/testutil"
+	"github.com/makiuchi-d/migy/migrate"
+	"github.com/makiuchi-d/migy/migrations"
+)
+
+func TestApplyTransaction(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewDB("db")
+	defer db.Close()
+
+	var warns []string
+	m := migrate.New(db, filepath.Join("testdata", "tx"))
+	m.Warn = func(msg string) { warns = append(warns, msg) }
+
+	if _, err := m.Up(ctx, -1); err != nil {
+		t.Fatal(err)
+	}
+	exp := []string{"000020_backfill.up.sql: \"ALTER TABLE `users` ADD COLUMN `memo` text\" causes an implicit commit in the transaction"}
+	if d := cmp.Diff(exp, warns); d != "" {
+		t.Fatalf("warnings on up:\n%v", d)
+	}
+
+	// down.sql does not have the annotation
+	warns = nil
+	if _, err := m.Down(ctx, 10); err != nil {
+		t.Fatal(err)
+	}
+	if len(warns) != 0 {
+		t.Fatalf("warnings on down: %v", warns)
+	}
+}
+
+func TestApplyTxGoMigration(t *testing.T) {
+	ctx := context.Background()
+	errNotTx := errors.New("not in a transaction")
+	inTx := func(db sqlx.Ext) error {
+		if _, ok := db.(*sqlx.Tx); !ok {
+			return errNotTx
+		}
+		return nil
+	}
+	db := testutil.NewDB("db")
+	defer db.Close()
+	m := migrate.New(db, filepath.Join("testdata", "gofunc"))
+	m.GoMigrations = []*migrations.Migration{migrations.Go(20, "in_tx", inTx, inTx)}
+
+	m.Tx = true
+	steps, err := m.Up(ctx, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 3 || steps[2].File != "000020_in_tx.up.go" {
+		t.Fatalf("steps: %+v", steps)
+	}
+
+	m.Tx = false
+	_, err = m.Down(ctx, 10)
+	if !errors.Is(err, errNotTx) {
+		t.Fatalf("must be errNotTx: %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/makiuchi-d/migy/dbstate"
+)
+
+// bookkeeping is the tables and the stored procedure of migy, which are not a part of the migrations.
+var bookkeeping = []string{"_migrations", "_migrations_progress", "_migration_exists"}
+
+// Verify returns the differences between the database and the state
+// rebuilt from the migration files up to the target number.
+// The records are compared only in the tables, and the bookkeeping of migy
+// ('_migrations' and '_migrations_progress' tables and '_migration_exists' procedure) is not compared,
+// so that the database without them can be verified before Baseline.
+// The tables of the database and the records of the tables are copied into a temporary database
+// to be compared in the same engine as the rebuilt state, since MySQL prints the schema differently.
+// A negative target means the latest migration.
+func (m *Migrator) Verify(ctx context.Context, target int, tables []string) (string, error) {
+	if m.db == nil {
+		return "", ErrNoDatabase
+	}
+	migs, err := m.load()
+	if err != nil {
+		return "", err
+	}
+	if target < 0 {
+		target = migs.Last().Number
+	}
+	i, err := migs.FindNumber(target)
+	if err != nil {
+		return "", err
+	}
+	files, err := migs[:i+1].FileNamesFromSnapshot()
+	if err != nil {
+		return "", err
+	}
+
+	sandbox, err := m.OpenSandbox(files)
+	if err != nil {
+		return "", err
+	}
+	defer sandbox.Close()
+
+	ss, err := dbstate.TakeSnapshot(sandbox)
+	if err != nil {
+		return "", err
+	}
+
+	dbcs, err := databaseCharset(m.db)
+	if err != nil {
+		return "", err
+	}
+	live, err := copyTables(m.db, tables, dbcs)
+	if err != nil {
+		return "", err
+	}
+	defer live.Close()
+
+	// the default character set of the database is the default of the temporary database in the copy
+	cs, err := databaseCharset(live)
+	if err != nil {
+		return "", err
+	}
+	for _, t := range ss.Tables {
+		t.Create = replaceTableCharset(t.Create, dbcs, fmt.Sprintf(" DEFAULT CHARSET=%s COLLATE=%s", cs.name, cs.collation))
+	}
+
+	// compare records only in the specified tables
+	ignores := make(map[string][]string, len(ss.Tables))
+	for name := range ss.Tables {
+		if !slices.Contains(tables, name) {
+			ignores[name] = []string{"*"}
+		}
+	}
+	ds, err := dbstate.Compare(live, ss, ignores)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(ds.Without(bookkeeping...).String(), "\n"), nil
+}
+
+// copyTables returns a temporary database with the tables of db except the bookkeeping
+// and the records of the given tables.
+// The tables in the default character set cs of db are created in the default of the temporary database.
+func copyTables(db *sqlx.DB, records []string, cs charset) (*sqlx.DB, error) {
+	tbls, err := dbstate.GetTables(db)
+	if err != nil {
+		return nil, err
+	}
+	sandbox := newSandboxDB("db")
+	for _, t := range tbls {
+		if slices.Contains(bookkeeping, t.Name) {
+			continue
+		}
+		if _, err := sandbox.Exec(replaceTableCharset(t.Create, cs, "")); err != nil {
+			sandbox.Close()
+			return nil, fmt.Errorf("table %s: %w", t.Name, err)
+		}
+		if !slices.Contains(records, t.Name) {
+			continue
+		}
+		if err := copyRecords(sandbox, db, t.Name); err != nil {
+			sandbox.Close()
+			return nil, fmt.Errorf("table %s: %w", t.Name, err)
+		}
+	}
+	return sandbox, nil
+}
+
+func copyRecords(dst, src *sqlx.DB, table string) error {
+	recs, err := dbstate.GetRecords(src, table)
+	if err != nil {
+		return err
+	}
+	if len(recs.Rows) == 0 {
+		return nil
+	}
+	cols := make([]string, len(recs.Columns))
+	for i, c := range recs.Columns {
+		cols[i] = "`" + c + "`"
+	}
+	q := fmt.Sprintf("INSERT INTO `%s` (%s) VALUES (%s)",
+		table, strings.Join(cols, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(cols)), ", "))
+	for _, r := range recs.Rows {
+		args := make([]any, len(r))
+		for i, v := range r {
+			args[i] = *v.(*any)
+		}
+		if _, err := dst.Exec(q, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// charset is the default character set and collation of a database.
+type charset struct {
+	name      string
+	collation string
+}
+
+func databaseCharset(db *sqlx.DB) (charset, error) {
+	var cs charset
+	err := db.QueryRow("SELECT @@character_set_database, @@collation_database").Scan(&cs.name, &cs.collation)
+	return cs, err
+}
+
+var reTableCharset = regexp.MustCompile(` DEFAULT CHARSET=(\w+)(?: COLLATE=(\w+))?`)
+
+// replaceTableCharset replaces the table options of the character set cs in the CREATE TABLE statement with repl.
+func replaceTableCharset(create string, cs charset, repl string) string {
+	return reTableCharset.ReplaceAllStringFunc(create, func(s string) string {
+		m := reTableCharset.FindStringSubmatch(s)
+		if m[1] != cs.name || (m[2] != "" && m[2] != cs.collation) {
+			return s
+		}
+		return repl
+	})
+}
@@ -0,0 +1,35 @@
+package migrate_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/makiuchi-d/migy/internal/testutil"
+	"github.com/makiuchi-d/migy/migrate"
+)
+
+func TestVerifyDefaultCollation(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewDB("db")
+	defer db.Close()
+
+	// the default collation of MySQL differs from the temporary database
+	for _, q := range []string{
+		"ALTER DATABASE `db` COLLATE utf8mb4_0900_ai_ci",
+		"CREATE TABLE `users` (`id` int NOT NULL PRIMARY KEY AUTO_INCREMENT, `name` varchar(255), `email` varchar(255))",
+	} {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := migrate.New(db, filepath.Join("testdata", "snapshot"))
+	diff, err := m.Verify(ctx, 20, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff != "" {
+		t.Fatalf("diff:\n%v", diff)
+	}
+}
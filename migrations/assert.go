@@ -0,0 +1,68 @@
+package migrations
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrAssertion = errors.New("assertion failed")
+
+// parseAsserts returns the queries of 'migy:assert' annotations in the file.
+func parseAsserts(file []byte) ([]string, error) {
+	var asserts []string
+	for _, m := range reAssert.FindAllStringSubmatch(string(file), -1) {
+		q := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(m[1]), ";"))
+		if q == "" {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFormat, strings.TrimSpace(m[0]))
+		}
+		asserts = append(asserts, q)
+	}
+	return asserts, nil
+}
+
+// Assert runs the assertion queries and returns an error for the first one that does not hold.
+// Each query must return a single value, which holds unless it is NULL, zero, false or empty.
+func Assert(db sqlx.Queryer, asserts []string) error {
+	for _, q := range asserts {
+		var v any
+		err := db.QueryRowx(q).Scan(&v)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: %s (got no rows)", ErrAssertion, q)
+		}
+		if err != nil {
+			return fmt.Errorf("assertion %q: %w", q, err)
+		}
+		if !truthy(v) {
+			if b, ok := v.([]byte); ok {
+				v = string(b)
+			}
+			return fmt.Errorf("%w: %s (got %v)", ErrAssertion, q, v)
+		}
+	}
+	return nil
+}
+
+func truthy(v any) bool {
+	var s string
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		s = fmt.Sprint(v)
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f != 0
+	}
+	return s != "" && !strings.EqualFold(s, "false")
+}
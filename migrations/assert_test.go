@@ -0,0 +1,52 @@
+package migrations_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/makiuchi-d/testdb"
+
+	"github.com/makiuchi-d/migy/migrations"
+)
+
+func TestAssert(t *testing.T) {
+	db := sqlx.NewDb(testdb.New("db"), "mysql")
+	defer db.Close()
+	for _, q := range []string{
+		"CREATE TABLE users (id int PRIMARY KEY, email varchar(255))",
+		"INSERT INTO users (id, email) VALUES (1, 'a@example.com'), (2, NULL)",
+	} {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := map[string]struct {
+		query string
+		err   error
+	}{
+		"true":   {"SELECT COUNT(*) = 2 FROM users", nil},
+		"count":  {"SELECT COUNT(*) FROM users", nil},
+		"string": {"SELECT email FROM users WHERE id = 1", nil},
+		"false":  {"SELECT COUNT(*) = 0 FROM users WHERE email IS NULL", migrations.ErrAssertion},
+		"zero":   {"SELECT COUNT(*) FROM users WHERE id > 2", migrations.ErrAssertion},
+		"null":   {"SELECT email FROM users WHERE id = 2", migrations.ErrAssertion},
+		"empty":  {"SELECT '' FROM users WHERE id = 1", migrations.ErrAssertion},
+		"norows": {"SELECT id FROM users WHERE id = 3", migrations.ErrAssertion},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := migrations.Assert(db, []string{test.query})
+			if !errors.Is(err, test.err) {
+				t.Fatalf("%v wants %v", err, test.err)
+			}
+		})
+	}
+
+	// query errors are not assertion failures
+	err := migrations.Assert(db, []string{"SELECT FROM"})
+	if err == nil || errors.Is(err, migrations.ErrAssertion) {
+		t.Errorf("must be a query error: %v", err)
+	}
+}
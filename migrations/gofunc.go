@@ -0,0 +1,91 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/makiuchi-d/migy/sqlfile"
+)
+
+// Func is a migration written in Go.
+type Func func(db sqlx.Ext) error
+
+// Go returns the migration of the number running the Go functions as the up/down migration.
+// It is given to Load, LoadFS or migrate.Migrator.GoMigrations to be merged with the SQL files.
+func Go(num int, title string, up, down Func) *Migration {
+	return &Migration{
+		Number:   num,
+		Title:    title,
+		UpDown:   true,
+		Ignores:  make(map[string][]string),
+		UpFunc:   up,
+		DownFunc: down,
+	}
+}
+
+// merge merges copies of the Go migrations into migs in number order.
+func merge(migs Migrations, gos []*Migration) (Migrations, error) {
+	for _, g := range gos {
+		if g.UpFunc == nil || g.DownFunc == nil {
+			return nil, fmt.Errorf("%06d_%s: nil function of Go migration", g.Number, g.Title)
+		}
+		i, found := slices.BinarySearchFunc(migs, g.Number, func(m *Migration, n int) int { return m.Number - n })
+		if found {
+			return nil, fmt.Errorf("%w: %s, %s", ErrDuplicateNumber, migs[i].UpName(), g.UpName())
+		}
+		m := *g
+		migs = slices.Insert(migs, i, &m)
+	}
+	return migs, nil
+}
+
+// IsGoFile reports whether the name is a file of a Go migration.
+func IsGoFile(name string) bool {
+	return strings.HasSuffix(name, ".up.go") || strings.HasSuffix(name, ".down.go")
+}
+
+// ApplyFS applies the file of the migration in fsys to db.
+// The up/down file of a Go migration runs the function instead,
+// which also records the history in '_migrations' table as the up/down.sql does.
+func (m *Migration) ApplyFS(db sqlx.Ext, fsys fs.FS, name string) error {
+	if !IsGoFile(name) {
+		return sqlfile.ApplyFS(db, fsys, name)
+	}
+
+	var err error
+	switch name {
+	case m.UpName():
+		err = m.applyUpFunc(db)
+	case m.DownName():
+		err = m.applyDownFunc(db)
+	default:
+		err = errors.New("not a file of the migration")
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+func (m *Migration) applyUpFunc(db sqlx.Ext) error {
+	const sql = "INSERT INTO _migrations (id, title, applied) VALUES (?, ?, now())"
+	if _, err := db.Exec(sql, m.Number, m.Title); err != nil {
+		return err
+	}
+	return m.UpFunc(db)
+}
+
+func (m *Migration) applyDownFunc(db sqlx.Ext) error {
+	if _, err := db.Exec(fmt.Sprintf("CALL _migration_exists(%d)", m.Number)); err != nil {
+		return err
+	}
+	if _, err := db.Exec("DELETE FROM _migrations WHERE id = ?", m.Number); err != nil {
+		return err
+	}
+	return m.DownFunc(db)
+}
@@ -0,0 +1,66 @@
+package migrations
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestGo(t *testing.T) {
+	nop := func(db sqlx.Ext) error { return nil }
+	g := Go(2, "gofunc", nop, nop)
+
+	migs, err := Load("testdata/migrations", g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var nums []int
+	for _, m := range migs {
+		nums = append(nums, m.Number)
+	}
+	if len(nums) != 5 || nums[1] != 1 || nums[2] != 2 || nums[3] != 3 {
+		t.Fatalf("numbers: %v", nums)
+	}
+	m := migs[2]
+	if !m.UpDown || m.UpName() != "000002_gofunc.up.go" || m.DownName() != "000002_gofunc.down.go" {
+		t.Fatalf("Go migration: %+v", m)
+	}
+	if m == g {
+		t.Fatalf("Go migration must be copied")
+	}
+	if !IsGoFile(m.UpName()) || IsGoFile(migs[1].UpName()) {
+		t.Fatalf("IsGoFile")
+	}
+
+	// the Go migrations are given for each load
+	migs, err = Load("testdata/migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migs) != 4 {
+		t.Fatalf("without Go migrations: %v", migs)
+	}
+
+	// only the Go migrations
+	migs, err = LoadFS(fstest.MapFS{}, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migs) != 1 || migs[0].Number != 2 {
+		t.Fatalf("Go only: %v", migs)
+	}
+
+	_, err = Load("testdata/migrations", Go(3, "dup", nop, nop))
+	if !errors.Is(err, ErrDuplicateNumber) {
+		t.Fatalf("duplicate number must be error: %v", err)
+	}
+	_, err = LoadFS(fstest.MapFS{}, g, Go(2, "again", nop, nop))
+	if !errors.Is(err, ErrDuplicateNumber) {
+		t.Fatalf("duplicate Go migration must be error: %v", err)
+	}
+	if _, err = LoadFS(fstest.MapFS{}, Go(4, "nil", nop, nil)); err == nil {
+		t.Fatalf("nil function must be error")
+	}
+}
@@ -9,8 +9,13 @@ import (
 
 type Status struct {
 	*Migration
-	Applied time.Time
-	DBTitle string // mismatched title
+	Applied  time.Time
+	DBTitle  string // mismatched title
+	Modified bool   // file modified after applied
+
+	// OutOfOrder is true if the migration is not applied but a later one is,
+	// e.g. a migration merged from another branch.
+	OutOfOrder bool
 }
 
 func (s Status) IsApplied() bool {
@@ -21,17 +26,51 @@ type History struct {
 	Id      int       `db:"id"`
 	Applied time.Time `db:"applied"`
 	Title   string    `db:"title"`
+	UpSum   string    `db:"up_checksum"`
+	DownSum string    `db:"down_checksum"`
 }
 
 type Histories []History
 
+// HasChecksumColumns reports whether '_migrations' table has the checksum columns.
+// '_migrations' tables created by older versions do not have them.
+func HasChecksumColumns(db sqlx.Queryer) (bool, error) {
+	rows, err := db.Query("SHOW COLUMNS FROM _migrations LIKE '%_checksum'")
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	n := 0
+	for rows.Next() {
+		n++
+	}
+	return n == 2, rows.Err()
+}
+
 func LoadHistories(db *sqlx.DB) (Histories, error) {
-	const sql = "SELECT id, applied, title FROM _migrations ORDER BY id"
+	sum, err := HasChecksumColumns(db)
+	if err != nil {
+		return nil, err
+	}
+	sql := "SELECT id, applied, title FROM _migrations ORDER BY id"
+	if sum {
+		sql = "SELECT id, applied, title," +
+			" IFNULL(up_checksum, '') AS up_checksum," +
+			" IFNULL(down_checksum, '') AS down_checksum" +
+			" FROM _migrations ORDER BY id"
+	}
 	var recs []History
-	err := db.Select(&recs, sql)
+	err = db.Select(&recs, sql)
 	return recs, err
 }
 
+// RecordChecksum stores the checksums of the migration files into the history.
+func RecordChecksum(db sqlx.Execer, m *Migration) error {
+	const sql = "UPDATE _migrations SET up_checksum = ?, down_checksum = ? WHERE id = ?"
+	_, err := db.Exec(sql, m.UpSum, m.DownSum, m.Number)
+	return err
+}
+
 func (hs Histories) CurrentNum() int {
 	if len(hs) == 0 {
 		return -1
@@ -41,47 +80,49 @@ func (hs Histories) CurrentNum() int {
 
 func BuildStatus(migs Migrations, hists []History) iter.Seq[Status] {
 	return func(yield func(Status) bool) {
+		cur := Histories(hists).CurrentNum()
 		var i, j int
 		for i < len(migs) && j < len(hists) {
 			m, h := migs[i], &hists[j]
 
 			if m.Number < h.Id {
-				if !yield(status(m, nil)) {
+				if !yield(status(m, nil, cur)) {
 					return
 				}
 				i++
 				continue
 			}
 			if m.Number > h.Id {
-				if !yield(status(nil, h)) {
+				if !yield(status(nil, h, cur)) {
 					return
 				}
 				j++
 				continue
 			}
-			if !yield(status(m, h)) {
+			if !yield(status(m, h, cur)) {
 				return
 			}
 			i++
 			j++
 		}
 		for ; i < len(migs); i++ {
-			if !yield(status(migs[i], nil)) {
+			if !yield(status(migs[i], nil, cur)) {
 				return
 			}
 		}
 		for ; j < len(hists); j++ {
-			if !yield(status(nil, &hists[j])) {
+			if !yield(status(nil, &hists[j], cur)) {
 				return
 			}
 		}
 	}
 }
 
-func status(m *Migration, h *History) Status {
+func status(m *Migration, h *History, cur int) Status {
 	if h == nil {
 		return Status{
-			Migration: m,
+			Migration:  m,
+			OutOfOrder: m.Number < cur,
 		}
 	}
 
@@ -102,5 +143,8 @@ func status(m *Migration, h *History) Status {
 	if m.Title != h.Title {
 		s.DBTitle = h.Title
 	}
+	if h.UpSum != "" && h.UpSum != m.UpSum || h.DownSum != "" && h.DownSum != m.DownSum {
+		s.Modified = true
+	}
 	return s
 }
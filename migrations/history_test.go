@@ -22,8 +22,8 @@ func TestLoadHistories(t *testing.T) {
 		"(10, '2025-09-15 11:22:33', 'first')"
 
 	exp := migrations.Histories{
-		{0, time.Date(2025, 9, 15, 10, 20, 30, 0, time.UTC), "init"},
-		{10, time.Date(2025, 9, 15, 11, 22, 33, 0, time.UTC), "first"},
+		{0, time.Date(2025, 9, 15, 10, 20, 30, 0, time.UTC), "init", "", ""},
+		{10, time.Date(2025, 9, 15, 11, 22, 33, 0, time.UTC), "first", "", ""},
 	}
 
 	db := sqlx.NewDb(testdb.New("db"), "mysql")
@@ -44,11 +44,59 @@ func TestLoadHistories(t *testing.T) {
 	}
 }
 
+func TestLoadHistoriesChecksum(t *testing.T) {
+	create := "CREATE TABLE _migrations (" +
+		"id      INTEGER NOT NULL," +
+		"applied DATETIME," +
+		"title   VARCHAR(255)," +
+		"up_checksum   CHAR(64)," +
+		"down_checksum CHAR(64)," +
+		"PRIMARY KEY (id))"
+	insert := "INSERT INTO _migrations (id, applied, title) VALUES" +
+		"(0, '2025-09-15 10:20:30', 'init')," +
+		"(10, '2025-09-15 11:22:33', 'first')"
+
+	exp := migrations.Histories{
+		{0, time.Date(2025, 9, 15, 10, 20, 30, 0, time.UTC), "init", "", ""},
+		{10, time.Date(2025, 9, 15, 11, 22, 33, 0, time.UTC), "first", "up10", "down10"},
+	}
+
+	db := sqlx.NewDb(testdb.New("db"), "mysql")
+	if _, err := db.Exec(create); err != nil {
+		t.Fatalf("db.Exec(create): %v", err)
+	}
+	if _, err := db.Exec(insert); err != nil {
+		t.Fatalf("db.Exec(insert): %v", err)
+	}
+
+	ok, err := migrations.HasChecksumColumns(db)
+	if err != nil {
+		t.Fatalf("HasChecksumColumns: %v", err)
+	}
+	if !ok {
+		t.Fatalf("HasChecksumColumns: %v, wants true", ok)
+	}
+
+	err = migrations.RecordChecksum(db, &migrations.Migration{Number: 10, UpSum: "up10", DownSum: "down10"})
+	if err != nil {
+		t.Fatalf("RecordChecksum: %v", err)
+	}
+
+	hs, err := migrations.LoadHistories(db)
+	if err != nil {
+		t.Fatalf("LoadHistories: %v", err)
+	}
+
+	if diff := cmp.Diff(hs, exp); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
 func TestCurrentNum(t *testing.T) {
 	hists := migrations.Histories{
-		{1, time.Date(2025, time.May, 10, 1, 4, 7, 0, time.Local), "first"},
-		{3, time.Date(2025, time.May, 11, 2, 5, 8, 0, time.Local), "third"},
-		{4, time.Date(2025, time.May, 12, 3, 6, 9, 0, time.Local), "fourth-db"},
+		{1, time.Date(2025, time.May, 10, 1, 4, 7, 0, time.Local), "first", "", ""},
+		{3, time.Date(2025, time.May, 11, 2, 5, 8, 0, time.Local), "third", "", ""},
+		{4, time.Date(2025, time.May, 12, 3, 6, 9, 0, time.Local), "fourth-db", "", ""},
 	}
 
 	exp := 4
@@ -74,24 +122,24 @@ func TestBuildHistories(t *testing.T) {
 	dt3 := time.Date(2025, time.May, 11, 2, 5, 8, 0, time.Local)
 	dt4 := time.Date(2025, time.May, 12, 3, 6, 9, 0, time.Local)
 	hists := []migrations.History{
-		{1, dt1, "first"},
-		{3, dt3, "third"},
-		{4, dt4, "fourth-db"},
+		{1, dt1, "first", "up1", "down1"},
+		{3, dt3, "third", "", ""},
+		{4, dt4, "fourth-db", "up4", "down4"},
 	}
 	migs := []*migrations.Migration{
-		{0, "init", false, true, nil},
-		{1, "first", true, false, nil},
-		{2, "second", true, true, nil},
-		{4, "fourth", true, false, nil},
-		{5, "fifth", true, false, nil},
+		{0, "init", false, true, false, nil, nil, nil, false, false, "", "", nil, nil},
+		{1, "first", true, false, false, nil, nil, nil, false, false, "up1", "down1", nil, nil},
+		{2, "second", true, true, false, nil, nil, nil, false, false, "up2", "down2", nil, nil},
+		{4, "fourth", true, false, false, nil, nil, nil, false, false, "up4", "down4-modified", nil, nil},
+		{5, "fifth", true, false, false, nil, nil, nil, false, false, "", "", nil, nil},
 	}
 	exp := []migrations.Status{
-		{&migrations.Migration{0, "init", false, true, nil}, time.Time{}, ""},
-		{&migrations.Migration{1, "first", true, false, nil}, dt1, ""},
-		{&migrations.Migration{2, "second", true, true, nil}, time.Time{}, ""},
-		{&migrations.Migration{3, "third", false, false, nil}, dt3, ""},
-		{&migrations.Migration{4, "fourth", true, false, nil}, dt4, "fourth-db"},
-		{&migrations.Migration{5, "fifth", true, false, nil}, time.Time{}, ""},
+		{&migrations.Migration{0, "init", false, true, false, nil, nil, nil, false, false, "", "", nil, nil}, time.Time{}, "", false, true},
+		{&migrations.Migration{1, "first", true, false, false, nil, nil, nil, false, false, "up1", "down1", nil, nil}, dt1, "", false, false},
+		{&migrations.Migration{2, "second", true, true, false, nil, nil, nil, false, false, "up2", "down2", nil, nil}, time.Time{}, "", false, true},
+		{&migrations.Migration{3, "third", false, false, false, nil, nil, nil, false, false, "", "", nil, nil}, dt3, "", false, false},
+		{&migrations.Migration{4, "fourth", true, false, false, nil, nil, nil, false, false, "up4", "down4-modified", nil, nil}, dt4, "fourth-db", true, false},
+		{&migrations.Migration{5, "fifth", true, false, false, nil, nil, nil, false, false, "", "", nil, nil}, time.Time{}, "", false, false},
 	}
 
 	var ss []migrations.Status
@@ -1,9 +1,11 @@
 package migrations
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io/fs"
 	"os"
-	"path/filepath"
 	"regexp"
 	"slices"
 	"strconv"
@@ -13,8 +15,10 @@ import (
 )
 
 var (
-	reFilenname = regexp.MustCompile(`^([0-9]+)_(.*)\.(up|down|all)\.sql$`)
+	reFilenname = regexp.MustCompile(`^([0-9]+)_(.*)\.(up|down|all|fixture)\.sql$`)
 	reIgnore    = regexp.MustCompile(`\smigy:ignore\s+(.*)+?(?:\n|$)`)
+	reAssert    = regexp.MustCompile(`\smigy:assert[ \t]+(.*)(?:\n|$)`)
+	reTx        = regexp.MustCompile(`\smigy:transaction(?:\s|$)`)
 )
 
 func parseSQLFileName(name string) (num int, title, kind string, ok bool) {
@@ -26,10 +30,16 @@ func parseSQLFileName(name string) (num int, title, kind string, ok bool) {
 	return n, m[2], m[3], true
 }
 
-// Load returns all migration SQL files in the dir.
+// Load returns all migration SQL files in the dir and the Go migrations.
 // This list is sorted by its number.
-func Load(dir string) (Migrations, error) {
-	dent, err := os.ReadDir(dir)
+func Load(dir string, gos ...*Migration) (Migrations, error) {
+	return LoadFS(os.DirFS(dir), gos...)
+}
+
+// LoadFS returns all migration SQL files in the root of fsys and the Go migrations.
+// This list is sorted by its number.
+func LoadFS(fsys fs.FS, gos ...*Migration) (Migrations, error) {
+	dent, err := fs.ReadDir(fsys, ".")
 	if err != nil {
 		return nil, err
 	}
@@ -69,10 +79,6 @@ func Load(dir string) (Migrations, error) {
 		m.kinds[kind] = name
 	}
 
-	if len(mm) == 0 {
-		return nil, ErrNoMigration
-	}
-
 	keys := maps.Keys(mm)
 	slices.Sort(keys)
 	migs := make([]*Migration, len(keys))
@@ -80,7 +86,8 @@ func Load(dir string) (Migrations, error) {
 		m := mm[num]
 
 		_, all := m.kinds["all"]
-		_, up := m.kinds["up"]
+		_, fixture := m.kinds["fixture"]
+		upname, up := m.kinds["up"]
 		downname, down := m.kinds["down"]
 
 		if !up && down {
@@ -89,33 +96,67 @@ func Load(dir string) (Migrations, error) {
 		if up && !down {
 			return nil, fmt.Errorf("%w down.sql: %06d", ErrMissingFile, num)
 		}
+		if fixture && !up {
+			return nil, fmt.Errorf("%w up.sql for fixture.sql: %06d", ErrMissingFile, num)
+		}
 
 		ignores := make(map[string][]string)
+		var upsum, downsum string
+		var upasserts, downasserts []string
+		var uptx, downtx bool
 		if down {
-			err := readIgnores(ignores, filepath.Join(dir, downname))
+			// read each file once and parse all annotations from it
+			upfile, err := fs.ReadFile(fsys, upname)
 			if err != nil {
+				return nil, err
+			}
+			downfile, err := fs.ReadFile(fsys, downname)
+			if err != nil {
+				return nil, err
+			}
+			if err := parseIgnores(ignores, downfile); err != nil {
 				return nil, fmt.Errorf("%v: %w", downname, err)
 			}
+			upasserts, err = parseAsserts(upfile)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %w", upname, err)
+			}
+			downasserts, err = parseAsserts(downfile)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %w", downname, err)
+			}
+			upsum, downsum = checksum(upfile), checksum(downfile)
+			uptx, downtx = reTx.Match(upfile), reTx.Match(downfile)
 		}
 
 		migs[i] = &Migration{
-			Number:   num,
-			Title:    m.title,
-			UpDown:   up,
-			Snapshot: all,
-			Ignores:  ignores,
+			Number:      num,
+			Title:       m.title,
+			UpDown:      up,
+			Snapshot:    all,
+			Fixture:     fixture,
+			Ignores:     ignores,
+			UpAsserts:   upasserts,
+			DownAsserts: downasserts,
+			UpTx:        uptx,
+			DownTx:      downtx,
+			UpSum:       upsum,
+			DownSum:     downsum,
 		}
 	}
 
-	return migs, nil
-}
-
-func readIgnores(igs map[string][]string, name string) error {
-	file, err := os.ReadFile(name)
+	migs, err = merge(migs, gos)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	if len(migs) == 0 {
+		return nil, ErrNoMigration
+	}
+	return migs, nil
+}
 
+// parseIgnores adds the columns of 'migy:ignore' annotations in the file to igs.
+func parseIgnores(igs map[string][]string, file []byte) error {
 	m := reIgnore.FindAllStringSubmatch(string(file), -1)
 
 	for _, s := range m {
@@ -130,3 +171,9 @@ func readIgnores(igs map[string][]string, name string) error {
 
 	return nil
 }
+
+// checksum returns the SHA-256 hex digest of the file content.
+func checksum(file []byte) string {
+	sum := sha256.Sum256(file)
+	return hex.EncodeToString(sum[:])
+}
@@ -2,7 +2,10 @@ package migrations
 
 import (
 	"errors"
+	"io/fs"
+	"os"
 	"testing"
+	"testing/fstest"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -14,6 +17,7 @@ func TestLoad(t *testing.T) {
 	}
 
 	emptymap := make(map[string][]string)
+	emptysum := "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
 
 	exp := Migrations{
 		{
@@ -29,6 +33,8 @@ func TestLoad(t *testing.T) {
 			UpDown:   true,
 			Snapshot: false,
 			Ignores:  emptymap,
+			UpSum:    emptysum,
+			DownSum:  emptysum,
 		},
 		{
 			Number:   3,
@@ -36,16 +42,21 @@ func TestLoad(t *testing.T) {
 			UpDown:   true,
 			Snapshot: true,
 			Ignores:  emptymap,
+			UpSum:    emptysum,
+			DownSum:  emptysum,
 		},
 		{
 			Number:   4,
 			Title:    "baz",
 			UpDown:   true,
 			Snapshot: false,
+			Fixture:  true,
 			// migy:ignore mytable.ignore1 mytable.ignore2
 			Ignores: map[string][]string{
 				"mytable": {"ignore1", "ignore2"},
 			},
+			UpSum:   emptysum,
+			DownSum: "e5f4eb580cd9d56df6cf67dc708a14100aadd369f1052a5bc32b85b6d1b0dd47",
 		},
 	}
 
@@ -54,14 +65,68 @@ func TestLoad(t *testing.T) {
 	}
 }
 
+func TestLoadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"000000_init.all.sql":   {Data: []byte("")},
+		"000010_foo.up.sql":     {Data: []byte("-- migy:assert SELECT 1\n-- migy:transaction")},
+		"000010_foo.down.sql":   {Data: []byte("-- migy:ignore t.c\n")},
+		"sub/000020_bar.up.sql": {Data: []byte("")},
+		"README.md":             {Data: []byte("")},
+	}
+	opens := make(map[string]int)
+	migs, err := LoadFS(countFS{fsys, opens})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(map[string]int{"000010_foo.up.sql": 1, "000010_foo.down.sql": 1}, opens); d != "" {
+		t.Errorf("each file must be read once:\n%v", d)
+	}
+
+	exp := Migrations{
+		{
+			Number:   0,
+			Title:    "init",
+			Snapshot: true,
+			Ignores:  map[string][]string{},
+		},
+		{
+			Number:    10,
+			Title:     "foo",
+			UpDown:    true,
+			Ignores:   map[string][]string{"t": {"c"}},
+			UpAsserts: []string{"SELECT 1"},
+			UpTx:      true,
+			UpSum:     "f3f40e52d232d92a1d6c869be8e095e29e1d08e03afd9e5f12a6888da856acfa",
+			DownSum:   "af7c95484528b77df1b5653b79d163ef709259f41133a483f9932541a74f644a",
+		},
+	}
+	if diff := cmp.Diff(migs, exp); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+// countFS counts opening the files.
+type countFS struct {
+	fs.FS
+	opens map[string]int
+}
+
+func (c countFS) Open(name string) (fs.File, error) {
+	if name != "." {
+		c.opens[name]++
+	}
+	return c.FS.Open(name)
+}
+
 func TestLoadFail(t *testing.T) {
 	tests := map[string]error{
-		"empty":       ErrNoMigration,
-		"duplicate":   ErrDuplicateNumber,
-		"mismatch":    ErrTitleMismatch,
-		"upmissing":   ErrMissingFile,
-		"downmissing": ErrMissingFile,
-		"invalidform": ErrInvalidFormat,
+		"empty":          ErrNoMigration,
+		"duplicate":      ErrDuplicateNumber,
+		"mismatch":       ErrTitleMismatch,
+		"upmissing":      ErrMissingFile,
+		"downmissing":    ErrMissingFile,
+		"fixturemissing": ErrMissingFile,
+		"invalidform":    ErrInvalidFormat,
 	}
 	for dir, exp := range tests {
 		t.Run(dir, func(t *testing.T) {
@@ -73,12 +138,15 @@ func TestLoadFail(t *testing.T) {
 	}
 }
 
-func TestReadIgnores(t *testing.T) {
-	igs := make(map[string][]string)
-	err := readIgnores(igs, "testdata/ignore/000001_ignore.down.sql")
+func TestParseIgnores(t *testing.T) {
+	file, err := os.ReadFile("testdata/ignore/000001_ignore.down.sql")
 	if err != nil {
 		t.Fatal(err)
 	}
+	igs := make(map[string][]string)
+	if err := parseIgnores(igs, file); err != nil {
+		t.Fatal(err)
+	}
 
 	exp := map[string][]string{
 		"table1": {"column1", "column2"},
@@ -89,3 +157,23 @@ func TestReadIgnores(t *testing.T) {
 		t.Fatal(diff)
 	}
 }
+
+func TestParseAsserts(t *testing.T) {
+	file, err := os.ReadFile("testdata/assert/000001_assert.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	asserts, err := parseAsserts(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []string{
+		"SELECT COUNT(*) = 0 FROM users WHERE email IS NULL",
+		"SELECT COUNT(*) FROM users",
+	}
+
+	if diff := cmp.Diff(asserts, exp); diff != "" {
+		t.Fatal(diff)
+	}
+}
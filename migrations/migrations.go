@@ -3,6 +3,7 @@ package migrations
 import (
 	"errors"
 	"fmt"
+	"slices"
 )
 
 var (
@@ -16,24 +17,40 @@ var (
 
 // Migration SQL file info
 type Migration struct {
-	Number   int
-	Title    string
-	UpDown   bool
-	Snapshot bool
-	Ignores  map[string][]string
+	Number      int
+	Title       string
+	UpDown      bool
+	Snapshot    bool
+	Fixture     bool // has rows to load before check
+	Ignores     map[string][]string
+	UpAsserts   []string // queries to hold after up.sql
+	DownAsserts []string // queries to hold after down.sql
+	UpTx        bool     // apply up.sql in a transaction
+	DownTx      bool     // apply down.sql in a transaction
+	UpSum       string   // checksum of up.sql
+	DownSum     string   // checksum of down.sql
+	UpFunc      Func     // Go migration instead of up.sql
+	DownFunc    Func     // Go migration instead of down.sql
 }
 
 // Migration list
 type Migrations []*Migration
 
-// UpName returns filename of '*.up.sql'.
+// UpName returns filename of '*.up.sql', or '*.up.go' for Go migration.
 func (m *Migration) UpName() string {
-	return fmt.Sprintf("%06d_%s.up.sql", m.Number, m.Title)
+	return fmt.Sprintf("%06d_%s.up.%s", m.Number, m.Title, m.ext())
 }
 
-// DownName returns filename of '*.down.sql'.
+// DownName returns filename of '*.down.sql', or '*.down.go' for Go migration.
 func (m *Migration) DownName() string {
-	return fmt.Sprintf("%06d_%s.down.sql", m.Number, m.Title)
+	return fmt.Sprintf("%06d_%s.down.%s", m.Number, m.Title, m.ext())
+}
+
+func (m *Migration) ext() string {
+	if m.UpFunc != nil {
+		return "go"
+	}
+	return "sql"
 }
 
 // SnapshotName returns filename of '*.all.sql' if exists
@@ -41,6 +58,11 @@ func (m *Migration) SnapshotName() string {
 	return fmt.Sprintf("%06d_%s.all.sql", m.Number, m.Title)
 }
 
+// FixtureName returns filename of '*.fixture.sql' if exists
+func (m *Migration) FixtureName() string {
+	return fmt.Sprintf("%06d_%s.fixture.sql", m.Number, m.Title)
+}
+
 // Last returns last migration
 func (migs Migrations) Last() *Migration {
 	return migs[len(migs)-1]
@@ -143,3 +165,64 @@ func (migs Migrations) FileNamesToApply(current, target int) ([]string, error) {
 		return files, nil
 	}
 }
+
+// FileNamesToApplyOutOfOrder returns the sql files to reach the target number based on the applied history
+// instead of the current number.
+// The unapplied migrations up to the target are upgraded in order of number, including the ones below the current number,
+// after the applied migrations beyond the target are downgraded in reverse order of application.
+func (migs Migrations) FileNamesToApplyOutOfOrder(hists Histories, target int) ([]string, error) {
+	t, err := migs.FindNumber(target)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := migs.FileNamesToRollback(hists, target)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]bool, len(hists))
+	for _, h := range hists {
+		applied[h.Id] = true
+	}
+	for _, m := range migs[:t+1] {
+		if applied[m.Number] {
+			continue
+		}
+		if !m.UpDown {
+			return nil, fmt.Errorf("%w: number=%06d", ErrSequenceGap, m.Number)
+		}
+		files = append(files, m.UpName())
+	}
+	return files, nil
+}
+
+// FileNamesToRollback returns the down sql files of the applied migrations beyond the target number
+// in reverse order of application. The unapplied migrations are skipped.
+func (migs Migrations) FileNamesToRollback(hists Histories, target int) ([]string, error) {
+	var downs Histories
+	for _, h := range hists {
+		if h.Id > target {
+			downs = append(downs, h)
+		}
+	}
+	slices.SortStableFunc(downs, func(a, b History) int {
+		if c := b.Applied.Compare(a.Applied); c != 0 {
+			return c
+		}
+		return b.Id - a.Id
+	})
+
+	var files []string
+	for _, h := range downs {
+		i, err := migs.FindNumber(h.Id)
+		if err != nil {
+			return nil, err
+		}
+		if !migs[i].UpDown {
+			return nil, fmt.Errorf("%w: number=%06d", ErrSequenceGap, h.Id)
+		}
+		files = append(files, migs[i].DownName())
+	}
+	return files, nil
+}
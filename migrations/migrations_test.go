@@ -3,6 +3,7 @@ package migrations
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -195,3 +196,48 @@ func TestFileNamesToApply(t *testing.T) {
 		}
 	}
 }
+
+func TestFileNamesToApplyOutOfOrder(t *testing.T) {
+	migs := Migrations{
+		{Number: 0, Title: "init", Snapshot: true},
+		{Number: 10, Title: "first", UpDown: true},
+		{Number: 20, Title: "second", UpDown: true},
+		{Number: 30, Title: "third", UpDown: true},
+		{Number: 40, Title: "fourth", UpDown: true},
+	}
+	t1 := time.Date(2025, time.May, 10, 1, 0, 0, 0, time.Local)
+	t2 := time.Date(2025, time.May, 11, 1, 0, 0, 0, time.Local)
+	// 20 was merged and applied after 30
+	hists := Histories{
+		{Id: 0, Applied: t1},
+		{Id: 10, Applied: t1},
+		{Id: 20, Applied: t2},
+		{Id: 30, Applied: t1},
+	}
+
+	tests := map[string]struct {
+		hists  Histories
+		target int
+		exp    []string
+		err    error
+	}{
+		"skipped 20":            {hists[:2:2], 20, []string{"000020_second.up.sql"}, nil},
+		"gap to 40":             {append(hists[:2:2], hists[3]), 40, []string{"000020_second.up.sql", "000040_fourth.up.sql"}, nil},
+		"gap to 10":             {append(hists[:2:2], hists[3]), 10, []string{"000030_third.down.sql"}, nil},
+		"down in applied order": {hists, 10, []string{"000020_second.down.sql", "000030_third.down.sql"}, nil},
+		"down and up":           {append(hists[:2:2], hists[3]), 20, []string{"000030_third.down.sql", "000020_second.up.sql"}, nil},
+		"nothing":               {hists, 30, nil, nil},
+		"snapshot":              {hists[1:], 10, nil, ErrSequenceGap},
+	}
+
+	for k, test := range tests {
+		files, err := migs.FileNamesToApplyOutOfOrder(test.hists, test.target)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%v: error=%q wants %q", k, err, test.err)
+			continue
+		}
+		if diff := cmp.Diff(test.exp, files); diff != "" {
+			t.Errorf("%q: %v", k, diff)
+		}
+	}
+}
@@ -0,0 +1,5 @@
+ALTER TABLE users MODIFY COLUMN email varchar(255) NOT NULL;
+-- migy:assert SELECT COUNT(*) = 0 FROM users WHERE email IS NULL;
+/*
+ * migy:assert SELECT COUNT(*) FROM users
+ */
@@ -0,0 +1,66 @@
+// Package migytest provides databases at a migration number for application tests.
+package migytest
+
+import (
+	"io/fs"
+	"os"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/makiuchi-d/migy/migrate"
+	"github.com/makiuchi-d/migy/migrations"
+)
+
+// CacheDir is the directory to cache the built states, shared with the migy command by default.
+// Empty disables the cache.
+var CacheDir = migrate.DefaultCacheDir()
+
+// DB returns a new in-memory database at the migration number in dir and the Go migrations.
+// A negative number means the latest migration.
+// The database is closed when the test finishes.
+func DB(t testing.TB, dir string, num int, gos ...*migrations.Migration) *sqlx.DB {
+	t.Helper()
+	return DBFS(t, os.DirFS(dir), num, gos...)
+}
+
+// DBFS returns a new in-memory database at the migration number in the root of fsys and the Go migrations.
+// A negative number means the latest migration.
+// The database is closed when the test finishes.
+//
+// The database is built from the latest snapshot and the following up migrations
+// by the sandbox of the migrate package, which restores the state cached in CacheDir,
+// so that the tests get fresh databases quickly without affecting each other.
+func DBFS(t testing.TB, fsys fs.FS, num int, gos ...*migrations.Migration) *sqlx.DB {
+	t.Helper()
+	files, err := fileNames(fsys, num, gos)
+	if err != nil {
+		t.Fatalf("migytest: %v", err)
+	}
+
+	m := migrate.NewFS(nil, fsys)
+	m.GoMigrations = gos
+	m.CacheDir = CacheDir
+	m.Warn = func(msg string) { t.Log("migytest:", msg) }
+	db, err := m.OpenSandbox(files)
+	if err != nil {
+		t.Fatalf("migytest: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func fileNames(fsys fs.FS, num int, gos []*migrations.Migration) ([]string, error) {
+	migs, err := migrations.LoadFS(fsys, gos...)
+	if err != nil {
+		return nil, err
+	}
+	if num >= 0 {
+		i, err := migs.FindNumber(num)
+		if err != nil {
+			return nil, err
+		}
+		migs = migs[:i+1]
+	}
+	return migs.FileNamesFromSnapshot()
+}
@@ -0,0 +1,81 @@
+package migytest_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/makiuchi-d/migy/dbstate"
+	"github.com/makiuchi-d/migy/migrations"
+	"github.com/makiuchi-d/migy/migytest"
+)
+
+func TestDB(t *testing.T) {
+	migytest.CacheDir = t.TempDir()
+	dir := filepath.Join("testdata", "snapshot")
+
+	db := migytest.DB(t, dir, 10)
+	hs, err := migrations.LoadHistories(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if num := hs.CurrentNum(); num != 10 {
+		t.Fatalf("current num = %v, wants 10", num)
+	}
+	if _, err := db.Exec("INSERT INTO users (id, name) VALUES (2, 'user2')"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the changes must not affect another database
+	db2 := migytest.DB(t, dir, 10)
+	recs, err := dbstate.GetRecords(db2, "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs.Rows) != 1 {
+		t.Fatalf("records: %v", recs.Rows)
+	}
+
+	if ents, err := os.ReadDir(migytest.CacheDir); err != nil || len(ents) == 0 {
+		t.Fatalf("state must be cached: %v, %v", ents, err)
+	}
+
+	latest := migytest.DB(t, dir, -1)
+	recs, err = dbstate.GetRecords(latest, "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "(1, 'user1', 'user1@example.com')"; len(recs.Rows) != 1 || recs.Rows[0].String() != exp {
+		t.Fatalf("records: %v wants %v", recs.Rows, exp)
+	}
+}
+
+func TestDBFS(t *testing.T) {
+	migytest.CacheDir = t.TempDir()
+	dir := filepath.Join("testdata", "snapshot")
+	ents, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{}
+	for _, e := range ents {
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fsys[e.Name()] = &fstest.MapFile{Data: data}
+	}
+
+	db := migytest.DBFS(t, fsys, 20)
+	if _, err := db.Exec("SELECT email FROM users"); err != nil {
+		t.Fatal(err)
+	}
+
+	// modified files must not hit the state of the original files
+	fsys["000020_alter_users.up.sql"].Data = []byte("CALL _migration_exists(10);\nINSERT INTO _migrations (id, applied, title) VALUES (20, now(), 'alter_users');\n")
+	db = migytest.DBFS(t, fsys, 20)
+	if _, err := db.Exec("SELECT email FROM users"); err == nil {
+		t.Fatal("email column exists")
+	}
+}
@@ -1,22 +1,111 @@
 package sqlfile
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
 )
 
-// Apply applies SQL file to db.DB
+// Error is an error of a statement in an SQL file.
+type Error struct {
+	File  string
+	Pos   Pos
+	Index int    // statement index, starting at 1
+	Stmt  string // failed statement
+	Err   error
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%v: statement %d: %v: %s", e.Pos, e.Index, e.Err, excerpt(e.Stmt))
+	if e.File == "" {
+		return msg
+	}
+	return e.File + ":" + msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+const excerptLen = 60
+
+// excerpt returns the statement in a line trimmed to a short length.
+func excerpt(stmt string) string {
+	s := strings.Join(strings.Fields(stmt), " ")
+	if r := []rune(s); len(r) > excerptLen {
+		s = string(r[:excerptLen]) + "..."
+	}
+	return s
+}
+
+// Apply applies SQL file to db.DB.
+// The file can be gzip-compressed.
 func Apply(db sqlx.Execer, file string) error {
-	input, err := os.ReadFile(file)
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return applyReader(db, file, f)
+}
+
+// ApplyFS applies SQL file in fsys to db.DB.
+// The file can be gzip-compressed.
+func ApplyFS(db sqlx.Execer, fsys fs.FS, name string) error {
+	f, err := fsys.Open(name)
 	if err != nil {
 		return err
 	}
-	for s := range Parse(input) {
-		_, err := db.Exec(s)
+	defer f.Close()
+	return applyReader(db, name, f)
+}
+
+// ApplyReader applies SQL statements read from r to db.DB.
+// The statements are read one by one, and the input can be gzip-compressed.
+func ApplyReader(db sqlx.Execer, r io.Reader) error {
+	return applyReader(db, "", r)
+}
+
+func applyReader(db sqlx.Execer, file string, r io.Reader) error {
+	s, err := NewFileScanner(r)
+	if err != nil {
+		return fileError(file, err)
+	}
+	i := 0
+	for s.Scan() {
+		i++
+		if _, err := db.Exec(s.Stmt()); err != nil {
+			return &Error{File: file, Pos: s.Pos(), Index: i, Stmt: s.Stmt(), Err: err}
+		}
+	}
+	return fileError(file, s.Err())
+}
+
+// NewFileScanner returns a new Scanner to read from r, which can be gzip-compressed.
+func NewFileScanner(r io.Reader) (*Scanner, error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); bytes.Equal(magic, gzipMagic) {
+		zr, err := gzip.NewReader(br)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		return NewScanner(zr), nil
+	}
+	return NewScanner(br), nil
+}
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+func fileError(file string, err error) error {
+	if err == nil || file == "" {
+		return err
 	}
-	return nil
+	return fmt.Errorf("%s: %w", file, err)
 }
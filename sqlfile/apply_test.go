@@ -1,7 +1,12 @@
 package sqlfile_test
 
 import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/jmoiron/sqlx"
@@ -53,3 +58,79 @@ func TestApply(t *testing.T) {
 		t.Fatal(diff)
 	}
 }
+
+func TestApplyFS(t *testing.T) {
+	db := sqlx.NewDb(testdb.New("db"), "mysql")
+
+	fsys := fstest.MapFS{
+		"a.sql": {Data: []byte("CREATE TABLE t (id int PRIMARY KEY);\nINSERT INTO t VALUES (1), (2);\n")},
+	}
+	if err := sqlfile.ApplyFS(db, fsys, "a.sql"); err != nil {
+		t.Fatal(err)
+	}
+
+	recs, err := dbstate.GetRecords(db, "t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs.Rows) != 2 {
+		t.Fatalf("unexpected records: %v", recs.Rows)
+	}
+
+	if err := sqlfile.ApplyFS(db, fsys, "missing.sql"); err == nil {
+		t.Fatal("must be error for missing file")
+	}
+}
+
+func TestApplyError(t *testing.T) {
+	db := sqlx.NewDb(testdb.New("db"), "mysql")
+
+	fsys := fstest.MapFS{
+		"a.sql": {Data: []byte("CREATE TABLE t (id int PRIMARY KEY);\n\nINSERT INTO t VALUES (1);\n  INSERT INTO nosuch\n  VALUES (1);\n")},
+	}
+	err := sqlfile.ApplyFS(db, fsys, "a.sql")
+	var serr *sqlfile.Error
+	if !errors.As(err, &serr) {
+		t.Fatalf("must be *sqlfile.Error: %v", err)
+	}
+	exp := sqlfile.Pos{Offset: 66, Line: 4, Column: 3}
+	if serr.File != "a.sql" || serr.Pos != exp || serr.Index != 3 {
+		t.Fatalf("error: %+v", serr)
+	}
+	if s, p := err.Error(), "a.sql:4:3: statement 3: "; !strings.HasPrefix(s, p) || !strings.HasSuffix(s, ": INSERT INTO nosuch VALUES (1)") {
+		t.Fatalf("message: %q", s)
+	}
+}
+
+func TestApplyReaderGzip(t *testing.T) {
+	db := sqlx.NewDb(testdb.New("db"), "mysql")
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte("CREATE TABLE t (id int PRIMARY KEY);\nINSERT INTO t VALUES (1), (2);\n"))
+	zw.Close()
+
+	fsys := fstest.MapFS{
+		"a.sql.gz": {Data: buf.Bytes()},
+		"b.sql":    {Data: []byte("INSERT INTO t VALUES (3);\n")},
+	}
+	if err := sqlfile.ApplyFS(db, fsys, "a.sql.gz"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sqlfile.ApplyReader(db, bytes.NewReader(fsys["b.sql"].Data)); err != nil {
+		t.Fatal(err)
+	}
+
+	recs, err := dbstate.GetRecords(db, "t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs.Rows) != 3 {
+		t.Fatalf("unexpected records: %v", recs.Rows)
+	}
+
+	err = sqlfile.ApplyReader(db, strings.NewReader("INSERT INTO nosuch VALUES (1);"))
+	if s := err.Error(); !strings.HasPrefix(s, "1:1: statement 1: ") {
+		t.Fatalf("message: %q", s)
+	}
+}
@@ -0,0 +1,46 @@
+package sqlfile
+
+import (
+	"strings"
+)
+
+// implicitCommits are the leading keywords of the statements which cause an implicit commit in MySQL.
+var implicitCommits = map[string]bool{
+	"ALTER":     true,
+	"CREATE":    true,
+	"DROP":      true,
+	"RENAME":    true,
+	"TRUNCATE":  true,
+	"GRANT":     true,
+	"REVOKE":    true,
+	"INSTALL":   true,
+	"UNINSTALL": true,
+	"LOCK":      true,
+	"UNLOCK":    true,
+	"BEGIN":     true,
+	"START":     true,
+	"COMMIT":    true,
+	"ANALYZE":   true,
+	"OPTIMIZE":  true,
+	"REPAIR":    true,
+	"FLUSH":     true,
+	"RESET":     true,
+	"CACHE":     true,
+}
+
+// ImplicitCommits returns the statements in the SQL string which cause an implicit commit in MySQL,
+// such as DDL statements. CREATE/DROP TEMPORARY TABLE are not included.
+func ImplicitCommits(input []byte) []string {
+	var stmts []string
+	for s := range Parse(input) {
+		words := strings.Fields(strings.ToUpper(s))
+		if len(words) == 0 || !implicitCommits[words[0]] {
+			continue
+		}
+		if len(words) > 1 && words[1] == "TEMPORARY" && (words[0] == "CREATE" || words[0] == "DROP") {
+			continue
+		}
+		stmts = append(stmts, s)
+	}
+	return stmts
+}
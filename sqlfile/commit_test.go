@@ -0,0 +1,27 @@
+package sqlfile
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestImplicitCommits(t *testing.T) {
+	input := []byte(`-- migy:transaction
+INSERT INTO _migrations (id, title, applied) VALUES (10, 'test', now());
+UPDATE users SET name = 'x';
+create table t1 (id int);
+CREATE TEMPORARY TABLE tmp (id int);
+/* comment */ ALTER TABLE users ADD COLUMN email text;
+DROP TEMPORARY TABLE tmp;
+TRUNCATE t1;
+`)
+	exp := []string{
+		"create table t1 (id int)",
+		"ALTER TABLE users ADD COLUMN email text",
+		"TRUNCATE t1",
+	}
+	if d := cmp.Diff(exp, ImplicitCommits(input)); d != "" {
+		t.Fatal(d)
+	}
+}
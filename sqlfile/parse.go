@@ -2,7 +2,9 @@ package sqlfile
 
 import (
 	"bytes"
+	"fmt"
 	"iter"
+	"regexp"
 	"strings"
 )
 
@@ -17,22 +19,42 @@ func skipSpaces(input []byte) (length int) {
 	return len(input)
 }
 
+// singlelineComment returns the length of '#' or '-- ' comment.
+// '--' starts a comment only if followed by a whitespace or control character as MySQL does.
 func singlelineComment(input []byte) (length int) {
-	if !bytes.HasPrefix(input, []byte("--")) {
+	switch {
+	case bytes.HasPrefix(input, []byte("#")):
+	case bytes.HasPrefix(input, []byte("--")):
+		if len(input) > 2 && input[2] > ' ' {
+			return 0
+		}
+	default:
 		return 0
 	}
-	for i := 2; i < len(input); i++ {
-		if input[i] == '\n' {
-			return i + 1
-		}
+	if i := bytes.IndexByte(input, '\n'); i >= 0 {
+		return i + 1
 	}
 	return len(input)
 }
 
+// multilineComment returns the length of '/* */' comment except for executable comments.
 func multilineComment(input []byte) (length int) {
-	if !bytes.HasPrefix(input, []byte("/*")) {
+	if !bytes.HasPrefix(input, []byte("/*")) || executableComment(input) > 0 {
 		return 0
 	}
+	return commentEnd(input)
+}
+
+// executableComment returns the length of '/*! */' or '/*+ */' comment,
+// which is a part of the statement.
+func executableComment(input []byte) (length int) {
+	if !bytes.HasPrefix(input, []byte("/*!")) && !bytes.HasPrefix(input, []byte("/*+")) {
+		return 0
+	}
+	return commentEnd(input)
+}
+
+func commentEnd(input []byte) int {
 	for i := 2; i < len(input)-1; i++ {
 		if input[i] == '*' && input[i+1] == '/' {
 			return i + 2
@@ -41,7 +63,13 @@ func multilineComment(input []byte) (length int) {
 	return len(input)
 }
 
-func quotedLiteral(input []byte) (length int) {
+// quotedLiteral returns the length of the quoted string or identifier.
+// A doubled quote is an escaped quote, and so is a backslash-escaped one in the strings
+// unless noBackslashEscapes.
+func quotedLiteral(input []byte, noBackslashEscapes bool) (length int) {
+	if len(input) == 0 {
+		return 0
+	}
 	quote := input[0]
 	switch quote {
 	case '\'', '"', '`':
@@ -49,16 +77,32 @@ func quotedLiteral(input []byte) (length int) {
 		return 0
 	}
 	for i := 1; i < len(input); i++ {
-		if input[i] == quote {
+		switch {
+		case input[i] == quote:
+			if i+1 < len(input) && input[i+1] == quote {
+				i++
+				continue
+			}
 			return i + 1
-		}
-		if input[i] == '\\' {
+		case input[i] == '\\' && quote != '`' && !noBackslashEscapes:
 			i++
 		}
 	}
 	return len(input)
 }
 
+var reSQLMode = regexp.MustCompile(`(?i)^(?:/\*!\d*\s*)?SET\s+(?:.*?[^@\w])?sql_mode\s*=\s*('[^']*'|"[^"]*"|[^\s,;*]+)`)
+
+// noBackslashEscapes reports whether the statement sets sql_mode with or without NO_BACKSLASH_ESCAPES.
+// The client can not know the mode changed in other ways, such as by stored procedures.
+func noBackslashEscapes(stmt string, cur bool) bool {
+	m := reSQLMode.FindStringSubmatch(stmt)
+	if m == nil {
+		return cur
+	}
+	return strings.Contains(strings.ToUpper(m[1]), "NO_BACKSLASH_ESCAPES")
+}
+
 func delimiter(input, delimiter []byte) bool {
 	return bytes.HasPrefix(input, delimiter)
 }
@@ -66,18 +110,18 @@ func delimiter(input, delimiter []byte) bool {
 func delimiterCmd(input []byte) (length int) {
 	const cmd = "DELIMITER"
 	p := 0
-	if bytes.HasPrefix(input, []byte("\\d")) {
-		p += 2
-	} else if len(input) > len(cmd)+1 {
-		if strings.ToUpper(string(input[:len(cmd)])) != cmd {
-			return 0
-		}
-		if c := input[len(cmd)]; c != ' ' && c != '\t' {
+	switch {
+	case bytes.HasPrefix(input, []byte("\\d")):
+		p = 2
+	case len(input) >= len(cmd) && strings.EqualFold(string(input[:len(cmd)]), cmd):
+		p = len(cmd)
+		if p < len(input) && skipSpaces(input[p:p+1]) == 0 {
 			return 0
 		}
-		p += len(cmd) + 1
+	default:
+		return 0
 	}
-	for input[p] == ' ' || input[p] == '\t' {
+	for p < len(input) && (input[p] == ' ' || input[p] == '\t') {
 		p++
 	}
 	return p
@@ -91,18 +135,17 @@ func changeDelimiter(input []byte) (delimiter []byte, length int) {
 
 	// DELIMITER command only takes the rest of the line.
 	line := input[p:]
-	if i := bytes.IndexByte(line, '\n'); i > 0 {
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
 		line = line[:i]
 	}
 
 	// quoted delimiter
-	if n := quotedLiteral(line); n > 0 {
-		delim := line[1 : n-1]
-		if line[0] != line[n-1] {
-			// unclosed
-			delim = line[1:n]
+	if n := quotedLiteral(line, false); n > 0 {
+		if n > 1 && line[0] == line[n-1] {
+			return line[1 : n-1], p + n
 		}
-		return delim, p + n
+		// unclosed
+		return line[1:n], p + n
 	}
 
 	for i := range len(line) {
@@ -115,60 +158,190 @@ func changeDelimiter(input []byte) (delimiter []byte, length int) {
 	return line, p + len(line)
 }
 
+// Pos is the position of a statement in an SQL string.
+type Pos struct {
+	Offset int // byte offset, starting at 0
+	Line   int // line number, starting at 1
+	Column int // byte column in the line, starting at 1
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
 // Parse extracts SQL statements from an SQL string
 func Parse(input []byte) iter.Seq[string] {
 	return func(yield func(string) bool) {
-		delim := []byte{';'}
-		inStmt := false
-		start := 0
-		p := 0
-		for p < len(input) {
-			if !inStmt {
-				if n := skipSpaces(input[p:]); n > 0 {
-					p += n
-					continue
-				}
+		for _, s := range ParsePos(input) {
+			if !yield(s) {
+				return
 			}
+		}
+	}
+}
 
-			if delimiter(input[p:], delim) {
-				if inStmt {
-					if !yield(string(input[start:p])) {
-						return
-					}
-				}
-				inStmt = false
-				p += len(delim)
-				continue
+// ParsePos extracts SQL statements with their positions from an SQL string
+func ParsePos(input []byte) iter.Seq2[Pos, string] {
+	return func(yield func(Pos, string) bool) {
+		ps := newParser(input, true)
+		for {
+			pos, stmt, ok, _ := ps.next()
+			if !ok || !yield(pos, stmt) {
+				return
 			}
+		}
+	}
+}
 
-			if d, n := changeDelimiter(input[p:]); n > 0 {
-				p += n
-				delim = d
-				continue
-			}
+// lookahead is the length to decide the kind of the next token.
+const lookahead = len("DELIMITER ")
+
+// parser splits SQL statements in buf.
+// The buf can be the beginning of the input and more input is appended while the last token is incomplete.
+type parser struct {
+	buf    []byte
+	eof    bool // buf reaches the end of the input
+	base   int  // offset of buf[0] in the input
+	pos    Pos  // position of buf[pos.Offset-base]
+	delim  []byte
+	nobs   bool
+	inStmt bool
+	start  int // start of the current statement in buf
+	p      int
+}
+
+func newParser(buf []byte, eof bool) *parser {
+	return &parser{
+		buf:   buf,
+		eof:   eof,
+		pos:   Pos{Line: 1, Column: 1},
+		delim: []byte{';'},
+	}
+}
+
+// position returns the position of buf[i].
+func (ps *parser) position(i int) Pos {
+	for j := ps.pos.Offset - ps.base; j < i; j++ {
+		if ps.buf[j] == '\n' {
+			ps.pos.Line++
+			ps.pos.Column = 1
+		} else {
+			ps.pos.Column++
+		}
+	}
+	ps.pos.Offset = ps.base + i
+	return ps.pos
+}
+
+// compact discards buf before the current statement or token and returns the kept length.
+func (ps *parser) compact() int {
+	keep := ps.p
+	if ps.inStmt {
+		keep = ps.start
+		ps.start = 0
+	}
+	ps.position(keep)
+	n := copy(ps.buf, ps.buf[keep:])
+	ps.buf = ps.buf[:n]
+	ps.base += keep
+	ps.p -= keep
+	return n
+}
+
+// next returns the next statement and its position.
+// ok is false at the end of the input, or when more input is required to find the statement.
+func (ps *parser) next() (pos Pos, stmt string, ok, more bool) {
+	// incomplete reports whether the token of length n may continue after buf
+	incomplete := func(n int) bool {
+		return !ps.eof && ps.p+n >= len(ps.buf)
+	}
+
+	for ps.p < len(ps.buf) {
+		rest := ps.buf[ps.p:]
+		if !ps.eof && (len(rest) < lookahead || len(rest) < len(ps.delim)) {
+			return Pos{}, "", false, true
+		}
 
-			if n := singlelineComment(input[p:]); n > 0 {
-				p += n
+		if !ps.inStmt {
+			if n := skipSpaces(rest); n > 0 {
+				ps.p += n
 				continue
 			}
-			if n := multilineComment(input[p:]); n > 0 {
-				p += n
-				continue
+		}
+
+		if delimiter(rest, ps.delim) {
+			ps.p += len(ps.delim)
+			if ps.inStmt {
+				ps.inStmt = false
+				stmt := string(ps.buf[ps.start : ps.p-len(ps.delim)])
+				ps.nobs = noBackslashEscapes(stmt, ps.nobs)
+				return ps.position(ps.start), stmt, true, false
 			}
-			if n := quotedLiteral(input[p:]); n > 0 {
-				p += n
+			continue
+		}
+
+		// DELIMITER command is only at the beginning of a statement but '\d' is anywhere.
+		if !ps.inStmt || rest[0] == '\\' {
+			if d, n := changeDelimiter(rest); n > 0 {
+				// the rest of the line is ignored
+				i := bytes.IndexByte(rest[n:], '\n')
+				if i < 0 && !ps.eof {
+					return Pos{}, "", false, true
+				}
+				if i < 0 {
+					ps.p = len(ps.buf)
+				} else {
+					ps.p += n + i + 1
+				}
+				if len(d) > 0 {
+					ps.delim = bytes.Clone(d) // d refers to buf
+				}
 				continue
 			}
+		}
 
-			if !inStmt {
-				inStmt = true
-				start = p
+		if n := singlelineComment(rest); n > 0 {
+			if incomplete(n) {
+				return Pos{}, "", false, true
 			}
-			p++
+			ps.p += n
+			continue
+		}
+		if n := multilineComment(rest); n > 0 {
+			if incomplete(n) {
+				return Pos{}, "", false, true
+			}
+			ps.p += n
+			continue
 		}
 
-		if inStmt && start != p {
-			yield(string(input[start:p]))
+		if !ps.inStmt {
+			ps.inStmt = true
+			ps.start = ps.p
 		}
+		if n := executableComment(rest); n > 0 {
+			if incomplete(n) {
+				return Pos{}, "", false, true
+			}
+			ps.p += n
+			continue
+		}
+		if n := quotedLiteral(rest, ps.nobs); n > 0 {
+			if incomplete(n) {
+				return Pos{}, "", false, true
+			}
+			ps.p += n
+			continue
+		}
+		ps.p++
+	}
+
+	if !ps.eof {
+		return Pos{}, "", false, true
+	}
+	if ps.inStmt {
+		ps.inStmt = false
+		return ps.position(ps.start), string(ps.buf[ps.start:ps.p]), true, false
 	}
+	return Pos{}, "", false, false
 }
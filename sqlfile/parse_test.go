@@ -1,7 +1,14 @@
 package sqlfile
 
 import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
 	"testing"
+	"testing/iotest"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 var testSQL = []byte(`-- test SQL
@@ -91,6 +98,9 @@ func TestSinglelineComment(t *testing.T) {
 		"empty":    {"--", 2},
 		"no1":      {"/--", 0},
 		"no2":      {"-/-", 0},
+		"nospace":  {"--abc\n", 0},
+		"tab":      {"--\tabc\ndef", len("--\tabc\n")},
+		"hash":     {"#abc\ndef", len("#abc\n")},
 	}
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
@@ -114,6 +124,8 @@ func TestMultilineComment(t *testing.T) {
 		"slash":    {"/*/*/*/", len("/*/*/")},
 		"no1":      {"-/*", 0},
 		"no2":      {"/-*", 0},
+		"exec":     {"/*!40101 SET x=1 */", 0},
+		"hint":     {"/*+ BKA(t1) */", 0},
 	}
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
@@ -147,19 +159,25 @@ func TestDelimiter(t *testing.T) {
 
 func TestQuotedLiteral(t *testing.T) {
 	tests := map[string]struct {
-		src string
-		exp int
+		src  string
+		nobs bool
+		exp  int
 	}{
-		"notquote": {"a'b\"c`def", 0},
-		"single":   {"'a\"b\\'c`d'ef", 10},
-		"double":   {"\"a\\\"b'c`d\"ef", 10},
-		"back":     {"`a\"b'c\\`d`ef", 10},
-		"empty":    {"''abc", 2},
-		"unclosed": {"'abc\"def", 8},
+		"notquote":      {"a'b\"c`def", false, 0},
+		"single":        {"'a\"b\\'c`d'ef", false, 10},
+		"double":        {"\"a\\\"b'c`d\"ef", false, 10},
+		"back":          {"`a\"b'c\\`d`ef", false, 8},
+		"back-doubled":  {"`a``b`c", false, 6},
+		"doubled":       {"'it''s'abc", false, 7},
+		"empty":         {"''abc", false, 2},
+		"unclosed":      {"'abc\"def", false, 8},
+		"nobs":          {"'a\\'bc'", true, 4},
+		"nobs-doubled":  {"'a\\''b'c", true, 7},
+		"nobs-unclosed": {"\"a\\\"b", true, 4},
 	}
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
-			n := quotedLiteral([]byte(test.src))
+			n := quotedLiteral([]byte(test.src), test.nobs)
 			if n != test.exp {
 				t.Errorf("length = %v wants %v", n, test.exp)
 			}
@@ -176,6 +194,7 @@ func TestChangeDelimiter(t *testing.T) {
 		"notcmd1":  {"select", "", 0},
 		"notcmd2":  {"delimiteraa", "", 0},
 		"notcmd3":  {"delimiter'aa'", "", 0},
+		"notcmd4":  {" t1;\n", "", 0},
 		"short1":   {"\\d//\n", "//", 4},
 		"short2":   {"\\d\t XXX -- comment", "XXX", 7},
 		"long":     {"Delimiter //\n", "//", 12},
@@ -240,3 +259,135 @@ END `,
 		t.Fatalf("not parsed: %v", exp[n:])
 	}
 }
+
+func TestParsePos(t *testing.T) {
+	input := []byte("-- comment\nSELECT 1;  SELECT 2;\n\n  /* c */ SELECT\n 3;")
+	exp := []Pos{
+		{Offset: 11, Line: 2, Column: 1},
+		{Offset: 22, Line: 2, Column: 12},
+		{Offset: 43, Line: 4, Column: 11},
+	}
+	var poss []Pos
+	for pos, stmt := range ParsePos(input) {
+		if s := string(input[pos.Offset : pos.Offset+len(stmt)]); s != stmt {
+			t.Errorf("offset %v: %q wants %q", pos.Offset, s, stmt)
+		}
+		poss = append(poss, pos)
+	}
+	if d := cmp.Diff(exp, poss); d != "" {
+		t.Fatal(d)
+	}
+}
+
+func TestParseMysqldump(t *testing.T) {
+	input, err := os.ReadFile("testdata/parse/mysqldump.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// position+length: excerpt
+	exp := []string{
+		"7:1+64: /*!40101 SET @OLD_CHARACTER_SET_CLIENT=@@CHARACTER_SET_CLIEN...",
+		"8:1+66: /*!40101 SET @OLD_CHARACTER_SET_RESULTS=@@CHARACTER_SET_RESU...",
+		"9:1+64: /*!40101 SET @OLD_COLLATION_CONNECTION=@@COLLATION_CONNECTIO...",
+		"10:1+29: /*!50503 SET NAMES utf8mb4 */",
+		"11:1+42: /*!40103 SET @OLD_TIME_ZONE=@@TIME_ZONE */",
+		"12:1+34: /*!40103 SET TIME_ZONE='+00:00' */",
+		"13:1+67: /*!40014 SET @OLD_UNIQUE_CHECKS=@@UNIQUE_CHECKS, UNIQUE_CHEC...",
+		"14:1+82: /*!40014 SET @OLD_FOREIGN_KEY_CHECKS=@@FOREIGN_KEY_CHECKS, F...",
+		"15:1+74: /*!40101 SET @OLD_SQL_MODE=@@SQL_MODE, SQL_MODE='NO_AUTO_VAL...",
+		"16:1+55: /*!40111 SET @OLD_SQL_NOTES=@@SQL_NOTES, SQL_NOTES=0 */",
+		"22:1+28: DROP TABLE IF EXISTS `items`",
+		"23:1+61: /*!40101 SET @saved_cs_client = @@character_set_client */",
+		"24:1+46: /*!50503 SET character_set_client = utf8mb4 */",
+		"25:1+293: CREATE TABLE `items` ( `id` int NOT NULL AUTO_INCREMENT, `na...",
+		"32:1+55: /*!40101 SET character_set_client = @saved_cs_client */",
+		"38:1+25: LOCK TABLES `items` WRITE",
+		"39:1+44: /*!40000 ALTER TABLE `items` DISABLE KEYS */",
+		"40:1+146: INSERT INTO `items` VALUES (1,'apple','red;\\'fresh\\'',1.50),...",
+		"41:1+43: /*!40000 ALTER TABLE `items` ENABLE KEYS */",
+		"42:1+13: UNLOCK TABLES",
+		"43:1+63: /*!50003 SET @saved_cs_client = @@character_set_client */",
+		"44:1+51: /*!50003 SET @saved_sql_mode = @@sql_mode */",
+		"45:1+160: /*!50003 SET sql_mode = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TAB...",
+		"47:1+191: /*!50003 CREATE*/ /*!50017 DEFINER=`root`@`localhost`*/ /*!5...",
+		"53:1+56: /*!50003 SET sql_mode = @saved_sql_mode */",
+		"58:1+44: /*!50003 DROP PROCEDURE IF EXISTS `total` */",
+		"59:1+63: /*!50003 SET sql_mode = 'NO_BACKSLASH_ESCAPES' */",
+		"61:1+220: CREATE DEFINER=`root`@`localhost` PROCEDURE `total`(OUT s de...",
+		"67:1+41: INSERT INTO `items` VALUES (4,'c:\\',0.00)",
+		"68:1+56: /*!50003 SET sql_mode = @saved_sql_mode */",
+		"69:1+40: /*!40103 SET TIME_ZONE=@OLD_TIME_ZONE */",
+		"71:1+38: /*!40101 SET SQL_MODE=@OLD_SQL_MODE */",
+		"72:1+58: /*!40014 SET FOREIGN_KEY_CHECKS=@OLD_FOREIGN_KEY_CHECKS */",
+		"73:1+62: /*!40101 SET CHARACTER_SET_CLIENT=@OLD_CHARACTER_SET_CLIENT ...",
+		"74:1+11: SELECT 1--1",
+		"75:1+40: SELECT /*+ MAX_EXECUTION_TIME(1000) */ 2",
+	}
+	var stmts []string
+	for pos, stmt := range ParsePos(input) {
+		stmts = append(stmts, fmt.Sprintf("%v+%d: %s", pos, len(stmt), excerpt(stmt)))
+	}
+	if d := cmp.Diff(exp, stmts); d != "" {
+		t.Fatal(d)
+	}
+}
+
+// FuzzParse checks the statement boundaries are consistent with the input.
+func FuzzParse(f *testing.F) {
+	dump, err := os.ReadFile("testdata/parse/mysqldump.sql")
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(dump)
+	f.Add(testSQL)
+	f.Add([]byte("SELECT 'a''b', \"c\\\"d\", `e``f`; # comment\nSELECT 1--1;\n-- comment\nSELECT /*! 2 */;"))
+	f.Add([]byte("SET sql_mode = 'NO_BACKSLASH_ESCAPES';\nSELECT 'c:\\';\nDELIMITER //\nSELECT 1; SELECT 2//\n\\d ;\nSELECT 3"))
+
+	f.Fuzz(func(t *testing.T, input []byte) {
+		line, col, off := 1, 1, 0
+		end := 0
+		for pos, stmt := range ParsePos(input) {
+			if stmt == "" || skipSpaces([]byte(stmt)) != 0 {
+				t.Fatalf("statement at %v starts with spaces: %q", pos, stmt)
+			}
+			if pos.Offset < end || pos.Offset+len(stmt) > len(input) {
+				t.Fatalf("statement at %v overlaps or overruns: end=%v len=%v", pos, end, len(input))
+			}
+			if s := string(input[pos.Offset : pos.Offset+len(stmt)]); s != stmt {
+				t.Fatalf("statement at %v = %q, wants %q", pos, stmt, s)
+			}
+			for ; off < pos.Offset; off++ {
+				if input[off] == '\n' {
+					line, col = line+1, 1
+				} else {
+					col++
+				}
+			}
+			if pos.Line != line || pos.Column != col {
+				t.Fatalf("position of offset %v = %v, wants %v:%v", pos.Offset, pos, line, col)
+			}
+			end = pos.Offset + len(stmt)
+		}
+
+		// the streaming scanner finds the same statements
+		stmts, err := scanAll(iotest.OneByteReader(bytes.NewReader(input)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := cmp.Diff(parseAll(input), stmts); d != "" {
+			t.Fatalf("scanner:\n%v", d)
+		}
+
+		// a trailing newline does not move the boundaries
+		var a, b []string
+		for s := range Parse(input) {
+			a = append(a, strings.TrimRight(s, " \t\r\n"))
+		}
+		for s := range Parse(append(input[:len(input):len(input)], '\n')) {
+			b = append(b, strings.TrimRight(s, " \t\r\n"))
+		}
+		if d := cmp.Diff(a, b); d != "" {
+			t.Fatalf("boundaries moved by a trailing newline:\n%v", d)
+		}
+	})
+}
@@ -0,0 +1,92 @@
+package sqlfile
+
+import (
+	"errors"
+	"io"
+)
+
+var ErrTooLong = errors.New("statement too long")
+
+// MaxStatementSize is the default maximum size of a statement for Scanner,
+// which is the default max_allowed_packet of MySQL 8.0.
+const MaxStatementSize = 64 * 1024 * 1024
+
+const minRead = 32 * 1024
+
+// Scanner reads SQL statements from an io.Reader one by one.
+// It buffers only the statement being read, up to the maximum size.
+type Scanner struct {
+	r    io.Reader
+	ps   *parser
+	max  int
+	pos  Pos
+	stmt string
+	err  error
+}
+
+// NewScanner returns a new Scanner to read from r.
+func NewScanner(r io.Reader) *Scanner {
+	return &Scanner{
+		r:   r,
+		ps:  newParser(nil, false),
+		max: MaxStatementSize,
+	}
+}
+
+// Buffer sets the maximum size of a statement.
+func (s *Scanner) Buffer(max int) {
+	s.max = max
+}
+
+// Scan advances to the next statement.
+// It returns false at the end of the input or an error.
+func (s *Scanner) Scan() bool {
+	for s.err == nil {
+		pos, stmt, ok, more := s.ps.next()
+		if ok {
+			s.pos, s.stmt = pos, stmt
+			return true
+		}
+		if !more {
+			return false
+		}
+		s.err = s.fill()
+	}
+	return false
+}
+
+// Stmt returns the statement found by Scan.
+func (s *Scanner) Stmt() string {
+	return s.stmt
+}
+
+// Pos returns the position of the statement found by Scan.
+func (s *Scanner) Pos() Pos {
+	return s.pos
+}
+
+// Err returns the first non-EOF error.
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+// fill reads more input into the buffer.
+func (s *Scanner) fill() error {
+	n := s.ps.compact()
+	buf := s.ps.buf
+	if n >= s.max {
+		return ErrTooLong
+	}
+	if c := min(max(2*cap(buf), n+minRead), s.max+lookahead); cap(buf)-n < minRead && c > cap(buf) {
+		nb := make([]byte, n, c)
+		copy(nb, buf)
+		buf = nb
+	}
+	m, err := s.r.Read(buf[n:cap(buf)])
+	s.ps.buf = buf[:n+m]
+	if errors.Is(err, io.EOF) {
+		s.ps.eof = true
+		return nil
+	}
+	return err
+}
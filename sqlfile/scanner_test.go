@@ -0,0 +1,100 @@
+package sqlfile
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type posStmt struct {
+	Pos  Pos
+	Stmt string
+}
+
+func scanAll(r io.Reader) ([]posStmt, error) {
+	var stmts []posStmt
+	s := NewScanner(r)
+	for s.Scan() {
+		stmts = append(stmts, posStmt{s.Pos(), s.Stmt()})
+	}
+	return stmts, s.Err()
+}
+
+func parseAll(input []byte) []posStmt {
+	var stmts []posStmt
+	for pos, stmt := range ParsePos(input) {
+		stmts = append(stmts, posStmt{pos, stmt})
+	}
+	return stmts
+}
+
+func TestScanner(t *testing.T) {
+	dump, err := os.ReadFile("testdata/parse/mysqldump.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	readers := map[string]func([]byte) io.Reader{
+		"whole":   func(b []byte) io.Reader { return bytes.NewReader(b) },
+		"onebyte": func(b []byte) io.Reader { return iotest.OneByteReader(bytes.NewReader(b)) },
+		"half":    func(b []byte) io.Reader { return iotest.HalfReader(bytes.NewReader(b)) },
+	}
+	for _, input := range [][]byte{dump, testSQL} {
+		exp := parseAll(input)
+		for name, newReader := range readers {
+			t.Run(name, func(t *testing.T) {
+				stmts, err := scanAll(newReader(input))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if d := cmp.Diff(exp, stmts); d != "" {
+					t.Fatal(d)
+				}
+			})
+		}
+	}
+}
+
+func TestScannerLarge(t *testing.T) {
+	// statements longer than the read size
+	var sb strings.Builder
+	for i := range 3 {
+		sb.WriteString("INSERT INTO t VALUES ('")
+		sb.WriteString(strings.Repeat(string(rune('a'+i)), 100*1024))
+		sb.WriteString("');\n")
+	}
+	input := []byte(sb.String())
+	stmts, err := scanAll(bytes.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(parseAll(input), stmts); d != "" {
+		t.Fatal(d)
+	}
+
+	s := NewScanner(bytes.NewReader(input))
+	s.Buffer(64 * 1024)
+	if s.Scan() {
+		t.Fatalf("must not scan: %v", s.Pos())
+	}
+	if !errors.Is(s.Err(), ErrTooLong) {
+		t.Fatalf("must be ErrTooLong: %v", s.Err())
+	}
+}
+
+func TestScannerError(t *testing.T) {
+	errRead := errors.New("read error")
+	r := io.MultiReader(strings.NewReader("SELECT 1; SELECT 2"), iotest.ErrReader(errRead))
+	stmts, err := scanAll(r)
+	if !errors.Is(err, errRead) {
+		t.Fatalf("must be errRead: %v", err)
+	}
+	if len(stmts) != 1 || stmts[0].Stmt != "SELECT 1" {
+		t.Fatalf("statements: %v", stmts)
+	}
+}
@@ -0,0 +1,5 @@
+-- Generated by migy (https://github.com/makiuchi-d/migy)
+CALL _migration_exists(20);
+DELETE FROM _migrations WHERE id = 20;
+-- Write your rollback SQL statements below.
+-- migy:assert SELECT COUNT(*) = 0 FROM _migrations WHERE id = 20
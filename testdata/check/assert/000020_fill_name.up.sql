@@ -0,0 +1,5 @@
+-- Generated by migy (https://github.com/makiuchi-d/migy)
+INSERT INTO _migrations (id, title, applied) VALUES (20, 'fill_name', now());
+-- Write your forward migration SQL statements below.
+UPDATE users SET name = 'unknown' WHERE name IS NULL;
+-- migy:assert SELECT COUNT(*) = 0 FROM users WHERE name = ''
@@ -0,0 +1,8 @@
+-- Generated by migy (https://github.com/makiuchi-d/migy)
+INSERT INTO _migrations (id, title, applied) VALUES (10, 'create', now());
+-- Write your forward migration SQL statements below.
+CREATE TABLE users (
+  id   int NOT NULL,
+  name varchar(100) NOT NULL DEFAULT '',
+  PRIMARY KEY (id)
+);
@@ -0,0 +1,8 @@
+-- Generated by migy (https://github.com/makiuchi-d/migy)
+CALL _migration_exists(20);
+DELETE FROM _migrations WHERE id = 20;
+-- Write your rollback SQL statements below.
+ALTER TABLE users ADD COLUMN name varchar(100) NOT NULL DEFAULT '' AFTER id;
+UPDATE users SET name = first_name;
+ALTER TABLE users DROP COLUMN first_name;
+ALTER TABLE users DROP COLUMN last_name;
@@ -0,0 +1,3 @@
+INSERT INTO users (id, name) VALUES
+  (1, 'Alice Smith'),
+  (2, 'Bob Jones');
@@ -0,0 +1,7 @@
+-- Generated by migy (https://github.com/makiuchi-d/migy)
+INSERT INTO _migrations (id, title, applied) VALUES (20, 'split_name', now());
+-- Write your forward migration SQL statements below.
+ALTER TABLE users ADD COLUMN first_name varchar(50) NOT NULL DEFAULT '' AFTER id;
+ALTER TABLE users ADD COLUMN last_name varchar(50) NOT NULL DEFAULT '' AFTER first_name;
+UPDATE users SET first_name = SUBSTRING_INDEX(name, ' ', 1), last_name = SUBSTRING_INDEX(name, ' ', -1);
+ALTER TABLE users DROP COLUMN name;
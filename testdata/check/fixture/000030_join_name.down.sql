@@ -0,0 +1,8 @@
+-- Generated by migy (https://github.com/makiuchi-d/migy)
+CALL _migration_exists(30);
+DELETE FROM _migrations WHERE id = 30;
+-- Write your rollback SQL statements below.
+ALTER TABLE users ADD COLUMN first_name varchar(50) NOT NULL DEFAULT '' AFTER id;
+ALTER TABLE users ADD COLUMN last_name varchar(50) NOT NULL DEFAULT '' AFTER first_name;
+UPDATE users SET first_name = SUBSTRING_INDEX(name, ' ', 1), last_name = SUBSTRING_INDEX(name, ' ', -1);
+ALTER TABLE users DROP COLUMN name;
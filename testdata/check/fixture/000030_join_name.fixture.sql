@@ -0,0 +1,3 @@
+INSERT INTO users (id, first_name, last_name) VALUES
+  (1, 'Alice', 'Smith'),
+  (2, 'Bob', 'Jones');
@@ -0,0 +1,7 @@
+-- Generated by migy (https://github.com/makiuchi-d/migy)
+INSERT INTO _migrations (id, title, applied) VALUES (30, 'join_name', now());
+-- Write your forward migration SQL statements below.
+ALTER TABLE users ADD COLUMN name varchar(100) NOT NULL DEFAULT '' AFTER id;
+UPDATE users SET name = CONCAT(first_name, ' ', last_name);
+ALTER TABLE users DROP COLUMN first_name;
+ALTER TABLE users DROP COLUMN last_name;
@@ -0,0 +1,10 @@
+-- Generated by migy (https://github.com/makiuchi-d/migy)
+INSERT INTO _migrations (id, title, applied) VALUES (10, 'create', now());
+-- Write your forward migration SQL statements below.
+CREATE TABLE table1 (
+  id  int NOT NULL,
+  val int NOT NULL DEFAULT 0,
+  cnt int NOT NULL DEFAULT 0,
+  PRIMARY KEY (id)
+);
+INSERT INTO table1 (id, val, cnt) VALUES (1, 0, 0);
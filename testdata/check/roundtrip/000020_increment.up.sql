@@ -0,0 +1,5 @@
+-- Generated by migy (https://github.com/makiuchi-d/migy)
+INSERT INTO _migrations (id, title, applied) VALUES (20, 'increment', now());
+-- Write your forward migration SQL statements below.
+UPDATE table1 SET val = val + 1;
+UPDATE table1 SET cnt = val;
@@ -0,0 +1,21 @@
+-- Generated by migy (https://github.com/makiuchi-d/migy)
+INSERT INTO _migrations (id, title, applied) VALUES (10, 'create_tables', now());
+-- Write your forward migration SQL statements below.
+
+CREATE TABLE users (
+  id    INTEGER NOT NULL,
+  name  VARCHAR(100) NOT NULL,
+  email VARCHAR(100) NOT NULL,
+  age   INTEGER,
+  PRIMARY KEY (id),
+  KEY idx_old (name),
+  KEY idx_age (age)
+);
+
+CREATE TABLE posts (
+  id      INTEGER NOT NULL,
+  user_id INTEGER NOT NULL,
+  body    TEXT,
+  PRIMARY KEY (id),
+  CONSTRAINT fk_posts_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
@@ -0,0 +1,23 @@
+CREATE TABLE users (
+  id    INTEGER NOT NULL,
+  email VARCHAR(255) NOT NULL,
+  name  VARCHAR(100) NOT NULL,
+  team_id INTEGER,
+  PRIMARY KEY (id),
+  KEY idx_name (name),
+  UNIQUE KEY uq_email (email)
+) COMMENT='users';
+
+CREATE TABLE teams (
+  id   INTEGER NOT NULL,
+  name VARCHAR(100) NOT NULL,
+  PRIMARY KEY (id)
+);
+
+CREATE TABLE memberships (
+  user_id  INTEGER NOT NULL,
+  team_id INTEGER NOT NULL,
+  PRIMARY KEY (user_id, team_id),
+  CONSTRAINT fk_memberships_user FOREIGN KEY (user_id) REFERENCES users (id),
+  CONSTRAINT fk_memberships_team FOREIGN KEY (team_id) REFERENCES teams (id)
+);
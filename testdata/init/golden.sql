@@ -1,9 +1,11 @@
 -- Generated by migy (https://github.com/makiuchi-d/migy)
 
 CREATE TABLE _migrations (
-   id      INTEGER NOT NULL,
-   applied DATETIME,
-   title   VARCHAR(255),
+   id            INTEGER NOT NULL,
+   applied       DATETIME,
+   title         VARCHAR(255),
+   up_checksum   CHAR(64),
+   down_checksum CHAR(64),
    PRIMARY KEY (id)
 );
 